@@ -0,0 +1,131 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+/***** exported functions *********************************************************/
+
+// Login authenticates with the username and password. A *MFARequiredError is
+// returned when the login must be finished with CompleteMFA
+func (client *Client) Login(ctx context.Context, username, password string) error {
+	response := json.RawMessage{}
+	if err := client.send(ctx, http.MethodPost, "/auth", &types.Authentication{
+		Username: username,
+		Password: password,
+	}, &response); err != nil {
+		return err
+	}
+
+	return client.acceptLogin(response)
+}
+
+// CompleteMFA finishes a login waiting on a second factor with a TOTP or recovery
+// code
+func (client *Client) CompleteMFA(ctx context.Context, challenge *types.MFAChallenge, code string) error {
+	response := json.RawMessage{}
+	if err := client.send(ctx, http.MethodPost, "/auth/mfa", &types.MFAVerification{
+		Challenge: challenge.Challenge,
+		Code:      code,
+	}, &response); err != nil {
+		return err
+	}
+
+	return client.acceptLogin(response)
+}
+
+// Refresh exchanges the refresh token cookie of the login for a new access token
+func (client *Client) Refresh(ctx context.Context) error {
+	client.refreshLock.Lock()
+	defer client.refreshLock.Unlock()
+
+	return client.refresh(ctx)
+}
+
+// Logout revokes the access token and the refresh tokens of the login
+func (client *Client) Logout(ctx context.Context) error {
+	if err := client.send(ctx, http.MethodPost, "/auth/logout", nil, nil); err != nil {
+		return err
+	}
+
+	client.SetToken("")
+	return nil
+}
+
+// Introspect returns the state of the access token or API key, the client must
+// be created with the credentials of a confidential client
+func (client *Client) Introspect(ctx context.Context, token string) (*types.Introspection, error) {
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.baseURL+"/oauth/introspect", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(client.clientId, client.clientSecret)
+
+	introspection := new(types.Introspection)
+	if err := client.do(req, introspection); err != nil {
+		return nil, err
+	}
+
+	return introspection, nil
+}
+
+// JWKS returns the public keys access tokens can be verified with
+func (client *Client) JWKS(ctx context.Context) (*types.JWKSet, error) {
+	keySet := new(types.JWKSet)
+	if err := client.send(ctx, http.MethodGet, "/.well-known/jwks.json", nil, keySet); err != nil {
+		return nil, err
+	}
+
+	return keySet, nil
+}
+
+/**********************************************************************************/
+
+// acceptLogin keeps the access token of a successful login, the response is the
+// token or the challenge of a login waiting on a second factor
+func (client *Client) acceptLogin(response json.RawMessage) error {
+	token := ""
+	if err := json.Unmarshal(response, &token); err == nil {
+		client.SetToken(token)
+		return nil
+	}
+
+	challenge := new(types.MFAChallenge)
+	if err := json.Unmarshal(response, challenge); err != nil {
+		return err
+	}
+
+	return &MFARequiredError{Challenge: challenge}
+}
+
+/**********************************************************************************/
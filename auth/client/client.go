@@ -0,0 +1,216 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+// Package client is the Go client of the auth service. The client keeps the
+// session and refresh cookies of a login and sends the access token as a bearer
+// token, expired access tokens are renewed with the refresh token:
+//
+//	authClient, err := client.New(client.Options{BaseURL: "http://127.0.0.1:8000"})
+//	err = authClient.Login(ctx, "kronedev@gmail.com", "password")
+//	users, err := authClient.ListUsers(ctx)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+/**********************************************************************************/
+
+// Options configures the client, the client id and secret are only needed to
+// introspect tokens
+type Options struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	// HTTPClient is copied for the requests, the copy is given a cookie jar when
+	// it has none
+	HTTPClient *http.Client
+}
+
+// New creates the client of the auth service at the base url
+func New(options Options) (*Client, error) {
+	if options.BaseURL == "" {
+		return nil, errors.New("the base url of the auth service is required")
+	}
+
+	// The caller's client is left untouched, the copy keeps the login cookies
+	httpClient := &http.Client{Timeout: defaultTimeout}
+	if options.HTTPClient != nil {
+		copied := *options.HTTPClient
+		httpClient = &copied
+	}
+	if httpClient.Jar == nil {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Jar = jar
+	}
+
+	return &Client{
+		baseURL:      strings.TrimSuffix(options.BaseURL, "/"),
+		clientId:     options.ClientID,
+		clientSecret: options.ClientSecret,
+		http:         httpClient,
+	}, nil
+}
+
+/***** Client *********************************************************************/
+
+// Client calls the auth service, it is safe for concurrent use
+type Client struct {
+	baseURL      string
+	clientId     string
+	clientSecret string
+	http         *http.Client
+	lock         sync.RWMutex
+	refreshLock  sync.Mutex
+	token        string
+}
+
+/***** exported functions *********************************************************/
+
+// Token returns the current access token, empty until a login succeeds
+func (client *Client) Token() string {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+
+	return client.token
+}
+
+// SetToken sets the access token sent as the bearer token, for tokens obtained
+// outside of the client
+func (client *Client) SetToken(token string) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.token = token
+}
+
+/**********************************************************************************/
+
+// call sends the request with the access token and decodes the response into the
+// result. A request refused because the access token expired is sent again once
+// the token has been renewed
+func (client *Client) call(ctx context.Context, method, path string, body, result interface{}) error {
+	token := client.Token()
+	err := client.send(ctx, method, path, body, result)
+	if !errors.Is(err, ErrUnauthorized) || token == "" {
+		return err
+	}
+
+	if refreshErr := client.renew(ctx, token); refreshErr != nil {
+		return err
+	}
+
+	return client.send(ctx, method, path, body, result)
+}
+
+// renew refreshes the access token that was refused, concurrent requests share a
+// single refresh since reusing the refresh token revokes the whole login
+func (client *Client) renew(ctx context.Context, refused string) error {
+	client.refreshLock.Lock()
+	defer client.refreshLock.Unlock()
+
+	// Another request already renewed the token
+	if client.Token() != refused {
+		return nil
+	}
+
+	return client.refresh(ctx)
+}
+
+// refresh exchanges the refresh cookie for a new access token, the caller holds
+// the refresh lock
+func (client *Client) refresh(ctx context.Context) error {
+	token := ""
+	if err := client.send(ctx, http.MethodPost, "/auth/refresh", nil, &token); err != nil {
+		return err
+	}
+
+	client.SetToken(token)
+	return nil
+}
+
+func (client *Client) send(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, client.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return client.do(req, result)
+}
+
+// do sends the request with the bearer token and decodes the response, responses
+// that are not successful are returned as an *Error
+func (client *Client) do(req *http.Request, result interface{}) error {
+	if token := client.Token(); token != "" && req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	response, err := client.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return newError(response.StatusCode, responseBytes)
+	}
+
+	if result == nil || len(responseBytes) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(responseBytes, result)
+}
+
+/**********************************************************************************/
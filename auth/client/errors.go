@@ -0,0 +1,105 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+// The errors matched with errors.Is by the status code of an *Error
+var (
+	ErrBadRequest      = &Error{StatusCode: http.StatusBadRequest}
+	ErrUnauthorized    = &Error{StatusCode: http.StatusUnauthorized}
+	ErrForbidden       = &Error{StatusCode: http.StatusForbidden}
+	ErrNotFound        = &Error{StatusCode: http.StatusNotFound}
+	ErrConflict        = &Error{StatusCode: http.StatusConflict}
+	ErrTooManyRequests = &Error{StatusCode: http.StatusTooManyRequests}
+)
+
+/***** Error **********************************************************************/
+
+// Error is the response of a request the auth service refused, the code is only
+// set by the OAuth2 endpoints
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func newError(statusCode int, body []byte) *Error {
+	err := &Error{StatusCode: statusCode}
+
+	// The service responds with a JSON string or, from the OAuth2 endpoints, with
+	// an OAuth2 error
+	oauthError := new(types.OAuthError)
+	switch {
+	case json.Unmarshal(body, &err.Message) == nil:
+	case json.Unmarshal(body, oauthError) == nil && oauthError.Error != "":
+		err.Code = oauthError.Error
+		err.Message = oauthError.Description
+	default:
+		err.Message = string(body)
+	}
+
+	if err.Message == "" {
+		err.Message = http.StatusText(statusCode)
+	}
+
+	return err
+}
+
+/***** exported functions *********************************************************/
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("auth service returned %d: %s", err.StatusCode, err.Message)
+}
+
+// Is matches the errors with the same status code
+func (err *Error) Is(target error) bool {
+	other, ok := target.(*Error)
+	return ok && other.StatusCode == err.StatusCode
+}
+
+/***** MFARequiredError ***********************************************************/
+
+// MFARequiredError is returned by a login that must be finished with a second
+// factor, the challenge is passed to CompleteMFA with the code
+type MFARequiredError struct {
+	Challenge *types.MFAChallenge
+}
+
+/***** exported functions *********************************************************/
+
+func (err *MFARequiredError) Error() string {
+	if err.Challenge.Enroll {
+		return "the login requires multi-factor authentication to be enrolled"
+	}
+
+	return "the login requires a multi-factor authentication code"
+}
+
+/**********************************************************************************/
@@ -0,0 +1,74 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+/***** exported functions *********************************************************/
+
+// Me returns the account of the logged in user
+func (client *Client) Me(ctx context.Context) (*types.UserInfo, error) {
+	user := new(types.UserInfo)
+	if err := client.call(ctx, http.MethodGet, "/me", nil, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdateProfile changes the profile of the logged in user, empty names, email and
+// phone are left unchanged while the address is always replaced
+func (client *Client) UpdateProfile(ctx context.Context, profile *types.UserProfile) (*types.UserInfo, error) {
+	updated := new(types.UserInfo)
+	if err := client.call(ctx, http.MethodPatch, "/me/profile", profile, updated); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// ChangePassword changes the password of the logged in user, the tokens of the
+// login are revoked so the user has to log in again
+func (client *Client) ChangePassword(ctx context.Context, current, password string) error {
+	return client.call(ctx, http.MethodPost, "/me/password", &types.PasswordChange{
+		Current:  current,
+		Password: password,
+	}, nil)
+}
+
+// DeleteMe removes the account of the logged in user, when the service allows it
+func (client *Client) DeleteMe(ctx context.Context) error {
+	if err := client.call(ctx, http.MethodDelete, "/me", nil, nil); err != nil {
+		return err
+	}
+
+	client.SetToken("")
+	return nil
+}
+
+/**********************************************************************************/
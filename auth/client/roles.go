@@ -0,0 +1,87 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+/***** exported functions *********************************************************/
+
+// ListRoles returns every role
+func (client *Client) ListRoles(ctx context.Context) ([]*types.Role, error) {
+	roles := make([]*types.Role, 0)
+	if err := client.call(ctx, http.MethodGet, "/roles", nil, &roles); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// GetRole returns the role with the name
+func (client *Client) GetRole(ctx context.Context, name string) (*types.Role, error) {
+	role := new(types.Role)
+	if err := client.call(ctx, http.MethodGet, rolePath(name), nil, role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// CreateRole adds the role
+func (client *Client) CreateRole(ctx context.Context, role *types.Role) (*types.Role, error) {
+	created := new(types.Role)
+	if err := client.call(ctx, http.MethodPost, rolePath(role.Name), role, created); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// UpdateRole replaces the role
+func (client *Client) UpdateRole(ctx context.Context, role *types.Role) (*types.Role, error) {
+	updated := new(types.Role)
+	if err := client.call(ctx, http.MethodPut, rolePath(role.Name), role, updated); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeleteRole removes the role, roles that are the parent of another role can't be
+// removed
+func (client *Client) DeleteRole(ctx context.Context, name string) error {
+	return client.call(ctx, http.MethodDelete, rolePath(name), nil, nil)
+}
+
+/**********************************************************************************/
+
+func rolePath(name string) string {
+	return "/roles/" + url.PathEscape(name)
+}
+
+/**********************************************************************************/
@@ -0,0 +1,103 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+/***** exported functions *********************************************************/
+
+// ListUsers returns the users the caller can see, admins see every user and callers
+// granted users:read see the users of their organization
+func (client *Client) ListUsers(ctx context.Context) ([]*types.UserInfo, error) {
+	users := make([]*types.UserInfo, 0)
+	if err := client.call(ctx, http.MethodGet, "/users", nil, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetUser returns the user with the username
+func (client *Client) GetUser(ctx context.Context, username string) (*types.UserInfo, error) {
+	user := new(types.UserInfo)
+	if err := client.call(ctx, http.MethodGet, userPath(username), nil, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CreateUser adds the user, the password is sent in plain text and hashed by the
+// service
+func (client *Client) CreateUser(ctx context.Context, user *types.User) (*types.UserInfo, error) {
+	created := new(types.UserInfo)
+	if err := client.call(ctx, http.MethodPost, "/users", user, created); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// UpdateUser applies the patch to the user
+func (client *Client) UpdateUser(ctx context.Context, username string, patch *types.UserPatch) (*types.UserInfo, error) {
+	updated := new(types.UserInfo)
+	if err := client.call(ctx, http.MethodPatch, userPath(username), patch, updated); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// ReplaceUser replaces the profile, roles, organization and claims of the user
+func (client *Client) ReplaceUser(ctx context.Context, user *types.User) (*types.UserInfo, error) {
+	updated := new(types.UserInfo)
+	if err := client.call(ctx, http.MethodPut, userPath(user.Username), user, updated); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// SetPassword sets the password of the user
+func (client *Client) SetPassword(ctx context.Context, username, password string) error {
+	return client.call(ctx, http.MethodPut, userPath(username)+"/password", &types.PasswordChange{Password: password}, nil)
+}
+
+// DeleteUser removes the user and revokes its tokens
+func (client *Client) DeleteUser(ctx context.Context, username string) error {
+	return client.call(ctx, http.MethodDelete, userPath(username), nil, nil)
+}
+
+/**********************************************************************************/
+
+func userPath(username string) string {
+	return "/users/" + url.PathEscape(username)
+}
+
+/**********************************************************************************/
@@ -29,6 +29,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/sdbeard/env/v7"
 	apicfg "github.com/sdbeard/go-supportlib/api/config"
@@ -46,12 +47,32 @@ var config *Configuration
 // Configuration holds all of the necessary files for configuring an authentication
 // and authorization service with JWTs
 type Configuration struct {
-	Dataplanes    map[string]configuration.DataplaneConnection `json:"dataplanes" env:"AUTH_DATAPLANES" envSeparator:","`
-	AwsConf       aws.ConnectConfig                            `json:"awsconnect" env:"AUTH_AWSCONF"`
-	ApiConf       apicfg.ListenerConfig                        `json:"api" env:"AUTH_APICONF"`
-	LogConf       logging.LogConfig                            `json:"log" env:"AUTH_LOGCONF"`
-	SecretsConf   secrets.ManagerConf                          `json:"secrets" env:"AUTH_SECRETSCONF"`
-	WorkingFolder string                                       `json:"-"`
+	Dataplanes        map[string]configuration.DataplaneConnection `json:"dataplanes" env:"AUTH_DATAPLANES" envSeparator:","`
+	AwsConf           aws.ConnectConfig                            `json:"awsconnect" env:"AUTH_AWSCONF"`
+	ApiConf           apicfg.ListenerConfig                        `json:"api" env:"AUTH_APICONF"`
+	LogConf           logging.LogConfig                            `json:"log" env:"AUTH_LOGCONF"`
+	SecretsConf       secrets.ManagerConf                          `json:"secrets" env:"AUTH_SECRETSCONF"`
+	JWTAlgorithm      string                                       `json:"jwtalgorithm" env:"AUTH_JWTALGORITHM"`
+	KeyRotation       time.Duration                                `json:"keyrotation" env:"AUTH_KEYROTATION"`
+	KeyGrace          time.Duration                                `json:"keygrace" env:"AUTH_KEYGRACE"`
+	LockoutThreshold  int                                          `json:"lockoutthreshold" env:"AUTH_LOCKOUTTHRESHOLD"`
+	LockoutDuration   time.Duration                                `json:"lockoutduration" env:"AUTH_LOCKOUTDURATION"`
+	LockoutMax        time.Duration                                `json:"lockoutmax" env:"AUTH_LOCKOUTMAX"`
+	TrustProxy        bool                                         `json:"trustproxy" env:"AUTH_TRUSTPROXY"`
+	EmailService      string                                       `json:"emailservice" env:"AUTH_EMAILSERVICE"`
+	EmailFrom         string                                       `json:"emailfrom" env:"AUTH_EMAILFROM"`
+	ResetURL          string                                       `json:"reseturl" env:"AUTH_RESETURL"`
+	ResetExpiry       time.Duration                                `json:"resetexpiry" env:"AUTH_RESETEXPIRY"`
+	VerifyMode        string                                       `json:"verifymode" env:"AUTH_VERIFYMODE"`
+	VerifyURL         string                                       `json:"verifyurl" env:"AUTH_VERIFYURL"`
+	MFAIssuer         string                                       `json:"mfaissuer" env:"AUTH_MFAISSUER"`
+	Issuer            string                                       `json:"issuer" env:"AUTH_ISSUER"`
+	LoginURL          string                                       `json:"loginurl" env:"AUTH_LOGINURL"`
+	IdentityProviders map[string]IdentityProvider                  `json:"identityproviders" env:"AUTH_IDPS"`
+	SessionStore      string                                       `json:"sessionstore" env:"AUTH_SESSIONSTORE"`
+	Audience          []string                                     `json:"audience" env:"AUTH_AUDIENCE" envSeparator:","`
+	SelfDelete        bool                                         `json:"selfdelete" env:"AUTH_SELFDELETE"`
+	WorkingFolder     string                                       `json:"-"`
 }
 
 /***** exported functions *********************************************************/
@@ -96,6 +117,9 @@ func ExtendedTypeParsers() map[reflect.Type]env.ParserFunc {
 	// Add the DataplaneMapParser
 	AddDataplaneMapParser(parserMap)
 
+	// Add the IdentityProviderMapParser
+	AddIdentityProviderMapParser(parserMap)
+
 	return parserMap
 }
 
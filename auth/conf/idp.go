@@ -0,0 +1,94 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/sdbeard/env/v7"
+)
+
+/***** IdentityProvider ***********************************************************/
+
+// IdentityProvider is an upstream OpenID Connect provider users can log in with,
+// users logging in for the first time are created in the organization with the
+// roles of the provider. The issuer is compared exactly as configured and verified
+// email addresses only link existing accounts when the provider is trusted
+type IdentityProvider struct {
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Organization string   `json:"org"`
+	Scopes       []string `json:"scopes"`
+	Roles        []string `json:"roles"`
+	TrustEmail   bool     `json:"trust_email"`
+}
+
+// UnmarshalJSON parses the identity provider and sets the defaults, the scopes
+// default to "openid profile email" and the roles are optional
+func (provider *IdentityProvider) UnmarshalJSON(data []byte) error {
+	type identityProvider IdentityProvider
+
+	parsed := identityProvider{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+	if parsed.Issuer == "" || parsed.ClientID == "" || parsed.Organization == "" {
+		return fmt.Errorf("the identity provider needs an issuer, client_id and org")
+	}
+
+	if len(parsed.Scopes) == 0 {
+		parsed.Scopes = []string{"openid", "profile", "email"}
+	}
+	if parsed.Roles == nil {
+		parsed.Roles = make([]string, 0)
+	}
+
+	*provider = IdentityProvider(parsed)
+	return nil
+}
+
+/**********************************************************************************/
+
+// AddIdentityProviderMapParser is the definition of the environment parsing
+// function for an environment variable to Map (map[string]IdentityProvider) This
+// parser assumes that the value is a JSON object keyed by the provider name:
+//
+//	{"[Name1]": {"issuer": "[Issuer]", "client_id": "[ClientID]", "client_secret": "[ClientSecret]",
+//	 "org": "[Organization]", "scopes": ["openid"], "roles": ["user"], "trust_email": false}}
+//
+// JSON keeps secrets and URLs containing separator characters intact
+func AddIdentityProviderMapParser(parsers map[reflect.Type]env.ParserFunc) {
+	parsers[reflect.TypeOf(map[string]IdentityProvider{})] = func(v string) (interface{}, error) {
+		parsedMap := make(map[string]IdentityProvider)
+		if err := json.Unmarshal([]byte(v), &parsedMap); err != nil {
+			return nil, fmt.Errorf("invalid format found in the identity providers: %w", err)
+		}
+
+		return parsedMap, nil
+	}
+}
+
+/**********************************************************************************/
@@ -4,7 +4,9 @@ go 1.21
 
 require (
 	github.com/golang-jwt/jwt/v5 v5.0.0
+	github.com/google/uuid v1.4.0
 	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/securecookie v1.1.1
 	github.com/gorilla/sessions v1.2.1
 	github.com/joho/godotenv v1.5.1
 	github.com/justinas/alice v1.2.0
@@ -12,6 +14,7 @@ require (
 	github.com/sdbeard/go-supportlib v0.0.0-20231031145300-61c6a838328b
 	github.com/sirupsen/logrus v1.9.3
 	github.com/unrolled/render v1.6.1
+	go.etcd.io/bbolt v1.3.8
 	golang.org/x/crypto v0.14.0
 )
 
@@ -41,13 +44,10 @@ require (
 	github.com/elastic/go-elasticsearch/v8 v8.10.1 // indirect
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/go-redis/redis/v8 v8.11.5 // indirect
-	github.com/google/uuid v1.4.0 // indirect
-	github.com/gorilla/securecookie v1.1.1 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/mitchellh/go-homedir v1.1.0 // indirect
 	github.com/rs/xid v1.5.0 // indirect
 	github.com/spf13/cast v1.5.1 // indirect
-	go.etcd.io/bbolt v1.3.8 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -30,6 +30,7 @@ import (
 	"os"
 	"os/signal"
 	"path"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -52,10 +53,14 @@ import (
 )
 
 var (
-	jwtSecretName        = "jwtsecretkey"
-	jwtRefreshSecretName = "jwtrefreshsecretkey"
-	sessionKeyName       = "sessionkey"
-	isInitialized        = util.FileExists(fmt.Sprintf("%s%s%s", conf.Get().WorkingFolder, string(os.PathSeparator), "auth.init"))
+	sessionKeyName    = "sessionkey"
+	refreshCookieName = "auth-refresh"
+	adminRole         = "sysadmin"
+	systemOrg         = "system"
+
+	// usersReadPermission lets callers that aren't admins see the users of their
+	// own organization
+	usersReadPermission = "users:read"
 )
 
 /**********************************************************************************/
@@ -68,6 +73,12 @@ func NewAuthService(sessionName string) (*AuthService, error) {
 
 	newService := &AuthService{
 		render: render.New(),
+		rotator: secure.NewKeyRotator(conf.Get().KeyRotation, map[string]string{
+			secure.AccessKeyName:       conf.Get().JWTAlgorithm,
+			secure.RefreshKeyName:      secure.DefaultAlgorithm,
+			secure.VerificationKeyName: secure.DefaultAlgorithm,
+			secure.MFAKeyName:          secure.DefaultAlgorithm,
+		}),
 	}
 
 	newService.RestService = rest.NewRestService(
@@ -75,29 +86,104 @@ func NewAuthService(sessionName string) (*AuthService, error) {
 		newService.initializeRouter,
 	)
 
+	// Sessions are kept in the client cookie unless a server side store is
+	// configured
+	sessionBackend, err := secure.NewSessionBackend(conf.Get().SessionStore)
+	if err != nil {
+		return nil, err
+	}
+
 	sessionSecret, _ := secure.GetSecret(sessionKeyName)
-	secure.InitSession(sessionSecret.Secret(), sessionName)
+	if sessionBackend != nil {
+		secure.InitServerSession(sessionSecret.Secret(), sessionName, sessionBackend)
+	} else {
+		secure.InitSession(sessionSecret.Secret(), sessionName)
+	}
+	middleware.SetAPIKeyResolver(newService.resolveAPIKey)
+
+	if err := newService.migrateInitState(); err != nil {
+		return nil, err
+	}
 
 	return newService, nil
 }
 
 func initSecrets() error {
-	if err := secure.LoadSecret(jwtSecretName, 16, 60); err != nil {
+	secure.SetKeyGracePeriod(conf.Get().KeyGrace)
+
+	// Access tokens are signed with the configured algorithm so that they can be
+	// verified with the published keys, refresh tokens never leave this service
+	if err := secure.LoadSigningKey(secure.AccessKeyName, conf.Get().JWTAlgorithm, time.Hour); err != nil {
 		return err
 	}
 
-	if err := secure.LoadSecret(jwtRefreshSecretName, 16, 60); err != nil {
+	if err := secure.LoadSigningKey(secure.RefreshKeyName, secure.DefaultAlgorithm, 24*time.Hour); err != nil {
+		return err
+	}
+
+	if err := secure.LoadSigningKey(secure.VerificationKeyName, secure.DefaultAlgorithm, 24*time.Hour); err != nil {
+		return err
+	}
+
+	if err := secure.LoadSigningKey(secure.MFAKeyName, secure.DefaultAlgorithm, 5*time.Minute); err != nil {
+		return err
+	}
+
+	return secure.LoadSecret(sessionKeyName, secure.MinSecretSize, 60)
+}
+
+// checkSecrets checks the secrets supplied with the enrollment before anything is
+// stored
+func checkSecrets(enrollment *types.Enrollment) error {
+	if enrollment.JWTSecret != nil {
+		if _, err := enrollment.JWTSecret.Duration(time.Hour); err != nil {
+			return err
+		}
+		if err := secure.CheckSigningKey(conf.Get().JWTAlgorithm, []byte(enrollment.JWTSecret.Value)); err != nil {
+			return fmt.Errorf("the jwt secret is refused: %s", err.Error())
+		}
+	}
+	if enrollment.SessionSecret != nil {
+		if _, err := enrollment.SessionSecret.Duration(time.Hour); err != nil {
+			return err
+		}
+		if len(enrollment.SessionSecret.Value) < secure.MinSecretSize {
+			return fmt.Errorf("the session secret must be at least %d bytes", secure.MinSecretSize)
+		}
+	}
+
+	return nil
+}
+
+// importSecrets persists the secrets supplied with the enrollment under the names
+// the service uses, the access token signing key and session key are only
+// generated when they aren't supplied
+func importSecrets(enrollment *types.Enrollment) error {
+	// Both secrets are checked before either is stored
+	if err := checkSecrets(enrollment); err != nil {
 		return err
 	}
 
-	return secure.LoadSecret(sessionKeyName, 8, 60)
+	if enrollment.JWTSecret != nil {
+		jwtExpiry, _ := enrollment.JWTSecret.Duration(time.Hour)
+		if err := secure.ImportSigningKey(secure.AccessKeyName, conf.Get().JWTAlgorithm, []byte(enrollment.JWTSecret.Value), jwtExpiry); err != nil {
+			return err
+		}
+	}
+	if enrollment.SessionSecret != nil {
+		sessionExpiry, _ := enrollment.SessionSecret.Duration(time.Hour)
+		return secure.ImportSecret(sessionKeyName, []byte(enrollment.SessionSecret.Value), sessionExpiry)
+	}
+
+	return nil
 }
 
 /**********************************************************************************/
 
 type AuthService struct {
 	*rest.RestService
-	render *render.Render
+	render  *render.Render
+	rotator *secure.KeyRotator
 }
 
 /***** exported functions *********************************************************/
@@ -111,6 +197,7 @@ func (auth *AuthService) Start() error {
 
 	stopChannel := auth.createStopChannel()
 
+	auth.rotator.Start()
 	go auth.RestService.StartSimple()
 
 	<-stopChannel
@@ -121,6 +208,7 @@ func (auth *AuthService) Start() error {
 
 // Stop initiaties the graceful shutdown of the API's underlying rest service
 func (auth *AuthService) Stop() {
+	auth.rotator.Stop()
 	auth.RestService.Stop()
 }
 
@@ -129,6 +217,10 @@ func (auth *AuthService) Stop() {
 func (auth *AuthService) initializeRouter(router *mux.Router) {
 	chain := alice.New(handlers.LoggingHandler, handlers.JSONContentTypeHandler)
 	authChain := alice.New(middleware.Authorization, handlers.LoggingHandler, handlers.JSONContentTypeHandler)
+	userInfoChain := alice.New(middleware.UserInfoAuthorization, handlers.LoggingHandler, handlers.JSONContentTypeHandler)
+	adminChain := authChain.Append(middleware.RequireRoles(adminRole))
+	usersReadChain := authChain.Append(middleware.RequireRoleOrPermission(adminRole, usersReadPermission))
+	formChain := alice.New(handlers.LoggingHandler)
 
 	router.Handle("/", chain.Then(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		auth.render.JSON(res, http.StatusOK, "service called")
@@ -145,11 +237,72 @@ func (auth *AuthService) initializeRouter(router *mux.Router) {
 
 	apitypes.BaselineAPI(router, chain)
 
+	router.Methods("GET").Path("/.well-known/jwks.json").Handler(chain.ThenFunc(auth.getJWKS))
+	router.Methods("GET").Path("/.well-known/openid-configuration").Handler(chain.ThenFunc(auth.openidConfiguration))
+	router.Methods("POST").Path("/keys/{name}/rotate").Handler(adminChain.ThenFunc(auth.rotateKey))
+	router.Methods("GET").Path("/audit").Handler(adminChain.ThenFunc(auth.getAudit))
 	router.Methods("POST").Path("/init").Handler(chain.ThenFunc(auth.init))
-	router.Methods("GET").Path("/users").Handler(authChain.ThenFunc(auth.getUsers))
-	router.Methods("POST").Path("/users").Handler(chain.ThenFunc(auth.addUser))
+	router.Methods("GET").Path("/users").Handler(usersReadChain.ThenFunc(auth.getUsers))
+	router.Methods("POST").Path("/users").Handler(adminChain.ThenFunc(auth.addUser))
+	router.Methods("GET").Path("/users/{id}").Handler(usersReadChain.ThenFunc(auth.getUserById))
+	router.Methods("PUT").Path("/users/{id}").Handler(adminChain.ThenFunc(auth.replaceUser))
+	router.Methods("PATCH").Path("/users/{id}").Handler(adminChain.ThenFunc(auth.patchUser))
+	router.Methods("DELETE").Path("/users/{id}").Handler(adminChain.ThenFunc(auth.deleteUser))
+	router.Methods("PUT").Path("/users/{id}/password").Handler(adminChain.ThenFunc(auth.setPassword))
+	router.Methods("GET").Path("/users/{id}/apikeys").Handler(adminChain.ThenFunc(auth.getAPIKeys))
+	router.Methods("DELETE").Path("/users/{id}/apikeys/{keyid}").Handler(adminChain.ThenFunc(auth.deleteAPIKey))
+	router.Methods("GET").Path("/users/{id}/sessions").Handler(adminChain.ThenFunc(auth.getSessions))
+	router.Methods("DELETE").Path("/users/{id}/sessions/{sessionid}").Handler(adminChain.ThenFunc(auth.deleteSession))
+	router.Methods("DELETE").Path("/users/{id}/mfa").Handler(adminChain.ThenFunc(auth.resetMFA))
+	router.Methods("GET").Path("/me").Handler(authChain.ThenFunc(auth.getMe))
+	router.Methods("DELETE").Path("/me").Handler(authChain.ThenFunc(auth.deleteMe))
+	router.Methods("PATCH").Path("/me/profile").Handler(authChain.ThenFunc(auth.patchMe))
+	router.Methods("POST").Path("/me/password").Handler(authChain.ThenFunc(auth.changeMyPassword))
+	router.Methods("GET").Path("/me/sessions").Handler(authChain.ThenFunc(auth.getSessions))
+	router.Methods("DELETE").Path("/me/sessions/{sessionid}").Handler(authChain.ThenFunc(auth.deleteSession))
+	router.Methods("GET").Path("/apikeys").Handler(authChain.ThenFunc(auth.getAPIKeys))
+	router.Methods("POST").Path("/apikeys").Handler(authChain.ThenFunc(auth.addAPIKey))
+	router.Methods("DELETE").Path("/apikeys/{keyid}").Handler(authChain.ThenFunc(auth.deleteAPIKey))
 	router.Methods("GET").Path("/roles").Handler(chain.ThenFunc(auth.getRoles))
+	router.Methods("GET").Path("/roles/{name}").Handler(authChain.ThenFunc(auth.getRole))
+	router.Methods("POST").Path("/roles/{name}").Handler(adminChain.ThenFunc(auth.addRole))
+	router.Methods("PUT").Path("/roles/{name}").Handler(adminChain.ThenFunc(auth.updateRole))
+	router.Methods("DELETE").Path("/roles/{name}").Handler(adminChain.ThenFunc(auth.deleteRole))
+	router.Methods("GET").Path("/orgs").Handler(adminChain.ThenFunc(auth.getOrgs))
+	router.Methods("GET").Path("/orgs/{name}").Handler(authChain.ThenFunc(auth.getOrg))
+	router.Methods("POST").Path("/orgs/{name}").Handler(adminChain.ThenFunc(auth.addOrg))
+	router.Methods("PUT").Path("/orgs/{name}").Handler(adminChain.ThenFunc(auth.updateOrg))
+	router.Methods("DELETE").Path("/orgs/{name}").Handler(adminChain.ThenFunc(auth.deleteOrg))
+	router.Methods("GET").Path("/orgs/{name}/members").Handler(usersReadChain.ThenFunc(auth.getMembers))
+	router.Methods("PUT").Path("/orgs/{name}/members/{username}").Handler(adminChain.ThenFunc(auth.addMember))
+	router.Methods("GET").Path("/clients").Handler(adminChain.ThenFunc(auth.getClients))
+	router.Methods("POST").Path("/clients").Handler(adminChain.ThenFunc(auth.addClient))
+	router.Methods("GET").Path("/clients/{id}").Handler(adminChain.ThenFunc(auth.getClient))
+	router.Methods("PUT").Path("/clients/{id}").Handler(adminChain.ThenFunc(auth.updateClient))
+	router.Methods("DELETE").Path("/clients/{id}").Handler(adminChain.ThenFunc(auth.deleteClient))
+	router.Methods("POST").Path("/clients/{id}/secret").Handler(adminChain.ThenFunc(auth.rotateClientSecret))
+	router.Methods("GET").Path("/oauth/authorize").Handler(formChain.ThenFunc(auth.authorize))
+	router.Methods("POST").Path("/oauth/token").Handler(formChain.ThenFunc(auth.oauthToken))
+	router.Methods("POST").Path("/oauth/introspect").Handler(formChain.ThenFunc(auth.introspect))
+	router.Methods("GET", "POST").Path("/userinfo").Handler(userInfoChain.ThenFunc(auth.userinfo))
+	router.Methods("GET").Path("/lockouts").Handler(adminChain.ThenFunc(auth.getLockouts))
+	router.Methods("GET").Path("/lockouts/{key}").Handler(adminChain.ThenFunc(auth.getLockout))
+	router.Methods("DELETE").Path("/lockouts/{key}").Handler(adminChain.ThenFunc(auth.unlock))
 	router.Methods("POST").Path("/auth").Handler(chain.ThenFunc(auth.authenticate))
+	router.Methods("GET").Path("/auth/federated").Handler(chain.ThenFunc(auth.getIdentityProviders))
+	router.Methods("GET").Path("/auth/federated/{provider}/login").Handler(formChain.ThenFunc(auth.federatedLogin))
+	router.Methods("GET").Path("/auth/federated/{provider}/callback").Handler(formChain.ThenFunc(auth.federatedCallback))
+	router.Methods("POST").Path("/auth/mfa").Handler(chain.ThenFunc(auth.completeMFA))
+	router.Methods("POST").Path("/auth/mfa/enroll").Handler(chain.ThenFunc(auth.enrollMFA))
+	router.Methods("POST").Path("/auth/mfa/confirm").Handler(chain.ThenFunc(auth.confirmMFA))
+	router.Methods("POST").Path("/auth/mfa/disable").Handler(authChain.ThenFunc(auth.disableMFA))
+	router.Methods("POST").Path("/auth/password/forgot").Handler(chain.ThenFunc(auth.forgotPassword))
+	router.Methods("POST").Path("/auth/password/reset").Handler(chain.ThenFunc(auth.resetPassword))
+	router.Methods("GET").Path("/auth/verify").Handler(chain.ThenFunc(auth.verifyEmail))
+	router.Methods("POST").Path("/auth/verify/resend").Handler(chain.ThenFunc(auth.resendVerification))
+	router.Methods("POST").Path("/auth/refresh").Handler(chain.ThenFunc(auth.refresh))
+	router.Methods("POST").Path("/auth/logout").Handler(chain.ThenFunc(auth.logout))
+	router.Methods("POST").Path("/auth/revoke").Handler(adminChain.ThenFunc(auth.revoke))
 	//router.Methods("GET").Path("/admin").Handler(authChain.ThenFunc(auth.adminIndex))
 	//router.Methods("GET").Path("/index").Handler(alice.New().ThenFunc(authapi.index))
 
@@ -169,30 +322,57 @@ func (auth *AuthService) init(res http.ResponseWriter, req *http.Request) {
 		res.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	}
 
-	if isInitialized {
+	// The HTTP initialization is only available until the service has been
+	// initialized, by this handler or by the seed command
+	initLock.Lock()
+	defer initLock.Unlock()
+
+	state, err := auth.getInitState()
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if state != nil {
+		auth.auditFailure(req, types.AuditInit, "", "", "already initialized")
 		auth.render.JSON(res, http.StatusUnauthorized, "the system has already been initialized, contact an administrator for credentials")
 		return
 	}
 
 	// Get the enrollment object
 	enrollment := new(types.Enrollment)
-	err := json.NewDecoder(req.Body).Decode(enrollment)
-	if err != nil {
+	if err = json.NewDecoder(req.Body).Decode(enrollment); err != nil {
 		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// Save the role and user
-	if err = auth.save(enrollment.Role); err != nil {
+	if err := checkSecrets(enrollment); err != nil {
+		auth.auditFailure(req, types.AuditInit, "", "", err.Error())
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := auth.enroll(enrollment); err != nil {
+		auth.auditFailure(req, types.AuditInit, "", "", err.Error())
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The secrets only replace the running ones once the enrollment succeeded
+	if err := importSecrets(enrollment); err != nil {
+		auth.auditFailure(req, types.AuditInit, "", "", err.Error())
 		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if enrollment.SessionSecret != nil {
+		secure.SetSessionSecret([]byte(enrollment.SessionSecret.Value))
+	}
 
-	if err := auth.saveUser(enrollment.User); err != nil {
+	if err := auth.save(types.NewInitState(initMethodHTTP, enrollment.User.Username)); err != nil {
 		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	auth.audit(req, types.AuditInit, enrollment.User.Username, initMethodHTTP)
 	auth.render.JSON(res, http.StatusOK, "successfully initialized the service")
 }
 
@@ -204,46 +384,60 @@ func (auth *AuthService) authenticate(res http.ResponseWriter, req *http.Request
 		return
 	}
 
-	user, err := auth.getUser(credentials.Username)
+	// Locked out usernames and addresses are refused before the password is checked
+	address := secure.ClientAddress(req)
+	locked, err := secure.LockedOut(credentials.Username, address)
 	if err != nil {
 		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
 		return
 	}
-
-	if check := secure.CheckPasswordHash(credentials.Password, user.Password); !check {
-		auth.render.JSON(res, http.StatusUnauthorized, "username or password is incorrect")
+	if locked > 0 {
+		auth.auditFailure(req, types.AuditLogin, credentials.Username, credentials.Username, "locked out")
+		res.Header().Set("Retry-After", strconv.Itoa(int(locked.Seconds())+1))
+		auth.render.JSON(res, http.StatusTooManyRequests, "too many failed login attempts, try again later")
 		return
 	}
 
-	jwtSecret, _ := secure.GetSecret(jwtSecretName)
-	token, err := secure.GenerateJWT(jwtSecret.Secret(), jwtSecret.Expiry, user)
+	user, err := auth.getUser(credentials.Username)
 	if err != nil {
-		auth.render.JSON(res, http.StatusUnauthorized, "failed to generate token")
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// Add the token to a gorilla session
-	if err := secure.SetSessionValue(req, res, "jwt", token); err != nil {
-		auth.render.JSON(res, http.StatusUnauthorized, err.Error())
+	// The same work is done and the same error is returned whether or not the user
+	// exists
+	if user == nil {
+		secure.CheckDummyPasswordHash(credentials.Password)
+	}
+
+	if user == nil || !secure.CheckPasswordHash(credentials.Password, user.Password) {
+		if err := secure.RecordLoginFailure(credentials.Username, address); err != nil {
+			logger.Errorf("failed to record the failed login for %s: %s", credentials.Username, err.Error())
+		}
+		auth.auditFailure(req, types.AuditLogin, credentials.Username, credentials.Username, "invalid credentials")
+
+		auth.render.JSON(res, http.StatusUnauthorized, "username or password is incorrect")
 		return
 	}
 
-	jwtRefreshSecret, _ := secure.GetSecret(jwtRefreshSecretName)
-	refreshToken, err := secure.GenerateRefreshJWT(jwtRefreshSecret.Secret(), user)
+	if err := secure.ClearLoginFailures(types.UserAttemptsKey(user.Id())); err != nil {
+		logger.Errorf("failed to clear the failed logins for %s: %s", user.Id(), err.Error())
+	}
+
+	// Users with MFA enabled, or holding a role that requires it, finish the login
+	// with a second factor
+	required, err := auth.mfaRequired(user)
 	if err != nil {
-		auth.render.JSON(res, http.StatusUnauthorized, "failed to generate refresh token")
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
 		return
 	}
-
-	cookie := http.Cookie{
-		Name:     "auth-refresh",
-		Value:    refreshToken,
-		HttpOnly: true,
-		Expires:  time.Now().Add(24 * time.Hour),
+	if required || user.MFAEnabled() {
+		auth.issueChallenge(res, user)
+		return
 	}
-	http.SetCookie(res, &cookie)
 
-	auth.render.JSON(res, http.StatusOK, token)
+	auth.audit(req, types.AuditLogin, user.Id(), user.Id())
+	auth.issueTokens(res, req, user, "")
 }
 
 func (auth *AuthService) getUsers(res http.ResponseWriter, req *http.Request) {
@@ -253,17 +447,27 @@ func (auth *AuthService) getUsers(res http.ResponseWriter, req *http.Request) {
 		res.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	}
 
-	users, err := dataservice.GetAll[*types.User](dataservice.Request{
-		Dataplane: conf.Get().Dataplanes[util.GetTypeName(types.User{})],
-	})
+	var users []*types.User
+	var err error
+
+	// Only admins can see the users of every organization
+	if middleware.HasRole(req, adminRole) {
+		users, err = dataservice.GetAll[*types.User](dataservice.Request{
+			Dataplane: conf.Get().Dataplanes[util.GetTypeName(types.User{})],
+		})
+	} else {
+		users, err = auth.getOrgMembers(middleware.Org(req))
+	}
 	if err != nil {
 		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	auth.render.JSON(res, http.StatusOK, users)
+	auth.render.JSON(res, http.StatusOK, types.NewUserInfoList(users))
 }
 
+// addUser creates a user, only admins can add users as the payload sets the roles,
+// claims and organization of the user
 func (auth *AuthService) addUser(res http.ResponseWriter, req *http.Request) {
 	if strings.Contains(req.RemoteAddr, "localhost") && strings.Contains("", "localhost") {
 		//Allow CORS here By * or specific origin
@@ -278,12 +482,41 @@ func (auth *AuthService) addUser(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if user.Username == "" || user.Password == "" {
+		auth.render.JSON(res, http.StatusBadRequest, "the username and password are required")
+		return
+	}
+	if err := types.ValidateClaims(user.Claims); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Adding the user must never overwrite an existing user
+	existing, err := auth.getUser(user.Username)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if existing != nil {
+		auth.render.JSON(res, http.StatusConflict, fmt.Sprintf("user %s already exists", user.Username))
+		return
+	}
+
+	if err := auth.validateOrg(user.Organization); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// New users always start with an unverified email address
+	user.EmailVerified = false
 	if err := auth.saveUser(user); err != nil {
 		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
 		return
 	}
+	go auth.sendVerification(user)
 
-	auth.render.JSON(res, http.StatusOK, user)
+	auth.audit(req, types.AuditUserCreate, middleware.Subject(req), user.Id())
+	auth.render.JSON(res, http.StatusCreated, types.NewUserInfo(user))
 }
 
 func (auth *AuthService) getRoles(res http.ResponseWriter, req *http.Request) {
@@ -310,13 +543,20 @@ func (auth *AuthService) getRoles(res http.ResponseWriter, req *http.Request) {
 
 /**********************************************************************************/
 
+// getUser returns the user with the passed in id, or nil if the user does not
+// exist
 func (auth *AuthService) getUser(userId string) (*types.User, error) {
-	return dataservice.GetItem[*types.User](dataservice.Request{
+	users, err := dataservice.Get[*types.User](dataservice.Request{
 		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.User{})],
 		Key:        "id",
 		Value:      userId,
 		Comparator: dsapi.EQ,
 	})
+	if err != nil || len(users) == 0 {
+		return nil, err
+	}
+
+	return users[0], nil
 }
 
 func (auth *AuthService) save(doc common.Document) error {
@@ -326,6 +566,15 @@ func (auth *AuthService) save(doc common.Document) error {
 	})
 }
 
+func (auth *AuthService) remove(doc common.Document) error {
+	return dataservice.Delete[common.Document](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(doc)],
+		Key:        doc.IdKey(),
+		Value:      doc.Id(),
+		Comparator: dsapi.EQ,
+	})
+}
+
 func (auth *AuthService) saveUser(user *types.User) error {
 	// Set the hashed password for the user
 	hashedPassword, err := secure.GenerateHashPassword(user.Password)
@@ -334,6 +583,9 @@ func (auth *AuthService) saveUser(user *types.User) error {
 	}
 	user.Password = hashedPassword
 
+	// MFA can only be set up by the user through enrollment
+	user.MFA = nil
+
 	// Save the user
 	return auth.save(user)
 }
@@ -0,0 +1,219 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+	logger "github.com/sirupsen/logrus"
+)
+
+var (
+	apiKeyPrefix = "ak_"
+	// lastUsedInterval limits how often the last used time of a key is saved
+	lastUsedInterval = time.Minute
+)
+
+/**********************************************************************************/
+
+// getAPIKeys returns the API keys of the caller, admins can list the keys of any
+// user
+func (auth *AuthService) getAPIKeys(res http.ResponseWriter, req *http.Request) {
+	apiKeys, err := auth.getUserAPIKeys(auth.targetUser(req))
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, types.NewAPIKeyInfoList(apiKeys))
+}
+
+// addAPIKey creates an API key for the caller, the key is only returned in this
+// response
+func (auth *AuthService) addAPIKey(res http.ResponseWriter, req *http.Request) {
+	keyRequest := new(types.APIKeyRequest)
+	if err := json.NewDecoder(req.Body).Decode(keyRequest); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if keyRequest.Name == "" {
+		auth.render.JSON(res, http.StatusBadRequest, "the key name is required")
+		return
+	}
+	if keyRequest.ExpiresIn < 0 {
+		auth.render.JSON(res, http.StatusBadRequest, "the key expiration can't be negative")
+		return
+	}
+
+	secret, err := secure.GenerateOpaqueToken(32)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	key := apiKeyPrefix + secret
+
+	apiKey := types.NewAPIKey(secure.HashToken(key), key[:len(apiKeyPrefix)+6], keyRequest.Name,
+		middleware.Subject(req), time.Duration(keyRequest.ExpiresIn)*time.Second)
+	if err := auth.save(apiKey); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditAPIKeyCreate, middleware.Subject(req), apiKey.KeyID)
+	auth.render.JSON(res, http.StatusCreated, types.NewAPIKeyInfo(apiKey, key))
+}
+
+// deleteAPIKey revokes an API key of the caller, admins can revoke the keys of any
+// user
+func (auth *AuthService) deleteAPIKey(res http.ResponseWriter, req *http.Request) {
+	apiKeys, err := auth.getUserAPIKeys(auth.targetUser(req))
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	keyId := mux.Vars(req)["keyid"]
+	for _, apiKey := range apiKeys {
+		if apiKey.KeyID != keyId {
+			continue
+		}
+
+		if err := auth.remove(apiKey); err != nil {
+			auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		auth.audit(req, types.AuditAPIKeyRevoke, middleware.Subject(req), apiKey.KeyID)
+		auth.render.JSON(res, http.StatusOK, "the API key has been revoked")
+		return
+	}
+
+	auth.render.JSON(res, http.StatusNotFound, "API key not found")
+}
+
+/**********************************************************************************/
+
+// resolveAPIKey is the middleware.APIKeyResolver of the service, the key resolves
+// to the current roles and permissions of the user it belongs to. Keys of a user
+// that is locked out or was revoked after the key was created are refused
+func (auth *AuthService) resolveAPIKey(key string) (jwt.MapClaims, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil
+	}
+
+	apiKeys, err := dataservice.Get[*types.APIKey](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.APIKey{})],
+		Key:        "id",
+		Value:      secure.HashToken(key),
+		Comparator: dsapi.EQ,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(apiKeys) == 0 || apiKeys[0].IsExpired() {
+		return nil, nil
+	}
+	apiKey := apiKeys[0]
+
+	user, err := auth.getUser(apiKey.Username)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	attempts, err := secure.GetLoginAttempts(types.UserAttemptsKey(user.Id()))
+	if err != nil {
+		return nil, err
+	}
+	if attempts != nil && attempts.IsLocked() {
+		return nil, nil
+	}
+
+	roles, permissions, err := auth.getPermissions(user)
+	if err != nil {
+		return nil, err
+	}
+	claims := secure.APIKeyClaims(apiKey, user, roles, permissions)
+
+	if revoked, err := secure.IsRevoked(claims); err != nil || revoked {
+		return nil, err
+	}
+
+	if time.Since(apiKey.LastUsed) > lastUsedInterval {
+		apiKey.LastUsed = time.Now()
+		if err := auth.save(apiKey); err != nil {
+			logger.Errorf("failed to record the use of API key %s: %s", apiKey.KeyID, err.Error())
+		}
+	}
+
+	return claims, nil
+}
+
+// targetUser returns the user whose keys or sessions are managed, the user in the
+// path for the admin routes and the caller otherwise
+func (auth *AuthService) targetUser(req *http.Request) string {
+	if username, ok := mux.Vars(req)["id"]; ok {
+		return username
+	}
+
+	return middleware.Subject(req)
+}
+
+// removeAPIKeys deletes all of the API keys of the user
+func (auth *AuthService) removeAPIKeys(username string) error {
+	apiKeys, err := auth.getUserAPIKeys(username)
+	if err != nil {
+		return err
+	}
+
+	for _, apiKey := range apiKeys {
+		if err := auth.remove(apiKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (auth *AuthService) getUserAPIKeys(username string) ([]*types.APIKey, error) {
+	return dataservice.Get[*types.APIKey](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.APIKey{})],
+		Key:        "username",
+		Value:      username,
+		Comparator: dsapi.EQ,
+	})
+}
+
+/**********************************************************************************/
@@ -0,0 +1,175 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+	logger "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+/**********************************************************************************/
+
+// getAudit returns the audit events selected by the query parameters, newest
+// first:
+//
+//	GET /audit?from=2023-10-01T00:00:00Z&to=1698333640&actor=user&event=login&limit=50
+func (auth *AuthService) getAudit(res http.ResponseWriter, req *http.Request) {
+	query, err := parseAuditQuery(req)
+	if err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, err := auth.findAuditEvents(query)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, events)
+}
+
+/**********************************************************************************/
+
+// audit records a successful event in the audit log
+func (auth *AuthService) audit(req *http.Request, event, actor, target string) {
+	auth.recordAudit(req, types.NewAuditEvent(event, actor, target, types.AuditSuccess, ""))
+}
+
+// auditFailure records a failed event in the audit log with the reason it failed
+func (auth *AuthService) auditFailure(req *http.Request, event, actor, target, reason string) {
+	auth.recordAudit(req, types.NewAuditEvent(event, actor, target, types.AuditFailure, reason))
+}
+
+// recordAudit adds the event to the audit log, failing to record the event is
+// logged and doesn't fail the request
+func (auth *AuthService) recordAudit(req *http.Request, event *types.AuditEvent) {
+	event.Address = secure.ClientAddress(req)
+	event.UserAgent = req.UserAgent()
+
+	if err := auth.save(event); err != nil {
+		logger.Errorf("failed to record the %s audit event for %s: %s", event.Event, event.Actor, err.Error())
+	}
+}
+
+func (auth *AuthService) findAuditEvents(query *types.AuditQuery) ([]*types.AuditEvent, error) {
+	request := dataservice.Request{
+		Dataplane: conf.Get().Dataplanes[util.GetTypeName(types.AuditEvent{})],
+	}
+
+	var events []*types.AuditEvent
+	var err error
+
+	// The actor, or the event type, narrows the events read from the dataplane and
+	// the rest of the query is matched here
+	switch {
+	case query.Actor != "":
+		request.Key, request.Value, request.Comparator = "actor", query.Actor, dsapi.EQ
+		events, err = dataservice.Get[*types.AuditEvent](request)
+	case query.Event != "":
+		request.Key, request.Value, request.Comparator = "event", query.Event, dsapi.EQ
+		events, err = dataservice.Get[*types.AuditEvent](request)
+	default:
+		events, err = dataservice.GetAll[*types.AuditEvent](request)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]*types.AuditEvent, 0)
+	for _, event := range events {
+		if query.Matches(event) {
+			found = append(found, event)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Time.After(found[j].Time)
+	})
+
+	if len(found) > query.Limit {
+		found = found[:query.Limit]
+	}
+
+	return found, nil
+}
+
+func parseAuditQuery(req *http.Request) (*types.AuditQuery, error) {
+	params := req.URL.Query()
+
+	query := &types.AuditQuery{
+		Actor: params.Get("actor"),
+		Event: params.Get("event"),
+		Limit: defaultAuditLimit,
+	}
+
+	var err error
+	if query.From, err = parseAuditTime(params.Get("from")); err != nil {
+		return nil, err
+	}
+	if query.To, err = parseAuditTime(params.Get("to")); err != nil {
+		return nil, err
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 {
+			return nil, errors.New("the limit must be a positive number")
+		}
+	}
+	if query.Limit > maxAuditLimit {
+		query.Limit = maxAuditLimit
+	}
+
+	return query, nil
+}
+
+// parseAuditTime accepts a time in RFC 3339 format or in seconds since the epoch
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+/**********************************************************************************/
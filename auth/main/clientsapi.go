@@ -0,0 +1,242 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+)
+
+/**********************************************************************************/
+
+func (auth *AuthService) getClients(res http.ResponseWriter, req *http.Request) {
+	clients, err := dataservice.Get[*types.Client](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.Client{})],
+		Key:        "type",
+		Value:      util.GetTypeName(types.Client{}),
+		Comparator: dsapi.EQ,
+	})
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, types.NewClientInfoList(clients))
+}
+
+func (auth *AuthService) getClient(res http.ResponseWriter, req *http.Request) {
+	client, ok := auth.lookupClient(res, req)
+	if !ok {
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, types.NewClientInfo(client, ""))
+}
+
+// addClient registers a new client, the generated secret is only returned in this
+// response
+func (auth *AuthService) addClient(res http.ResponseWriter, req *http.Request) {
+	registration := new(types.ClientRegistration)
+	if err := json.NewDecoder(req.Body).Decode(registration); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if registration.Name == "" {
+		auth.render.JSON(res, http.StatusBadRequest, "the client name is required")
+		return
+	}
+
+	if registration.Organization == "" {
+		registration.Organization = middleware.Org(req)
+	}
+	if err := auth.validateOrg(registration.Organization); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	client := types.NewClient(registration)
+	if client.Scopes == nil {
+		client.Scopes = make([]string, 0)
+	}
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = make([]string, 0)
+	}
+
+	// Public clients can't keep a secret so they aren't given one
+	if client.Public {
+		if err := auth.save(client); err != nil {
+			auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		auth.render.JSON(res, http.StatusCreated, types.NewClientInfo(client, ""))
+		return
+	}
+
+	secret, err := auth.setClientSecret(client)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusCreated, types.NewClientInfo(client, secret))
+}
+
+func (auth *AuthService) updateClient(res http.ResponseWriter, req *http.Request) {
+	client, ok := auth.lookupClient(res, req)
+	if !ok {
+		return
+	}
+
+	registration := new(types.ClientRegistration)
+	if err := json.NewDecoder(req.Body).Decode(registration); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if registration.Organization != "" && registration.Organization != client.Organization {
+		if err := auth.validateOrg(registration.Organization); err != nil {
+			auth.render.JSON(res, http.StatusBadRequest, err.Error())
+			return
+		}
+		client.Organization = registration.Organization
+	}
+	if registration.Name != "" {
+		client.Name = registration.Name
+	}
+	if registration.Scopes != nil {
+		client.Scopes = registration.Scopes
+	}
+	if registration.RedirectURIs != nil {
+		client.RedirectURIs = registration.RedirectURIs
+	}
+	if registration.Active != nil {
+		client.Active = *registration.Active
+	}
+
+	if err := auth.save(client); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, types.NewClientInfo(client, ""))
+}
+
+func (auth *AuthService) deleteClient(res http.ResponseWriter, req *http.Request) {
+	client, ok := auth.lookupClient(res, req)
+	if !ok {
+		return
+	}
+
+	if err := auth.remove(client); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Tokens already issued to the client must stop working with the client gone
+	if err := auth.revokeSubject(client.Id(), "client deleted"); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, fmt.Sprintf("successfully deleted client %s", client.Name))
+}
+
+// rotateClientSecret replaces the secret of the client, the old secret stops
+// working immediately
+func (auth *AuthService) rotateClientSecret(res http.ResponseWriter, req *http.Request) {
+	client, ok := auth.lookupClient(res, req)
+	if !ok {
+		return
+	}
+
+	if client.Public {
+		auth.render.JSON(res, http.StatusBadRequest, "public clients do not have a secret")
+		return
+	}
+
+	secret, err := auth.setClientSecret(client)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditSecretAccess, middleware.Subject(req), client.ClientID)
+	auth.render.JSON(res, http.StatusOK, types.NewClientInfo(client, secret))
+}
+
+/**********************************************************************************/
+
+func (auth *AuthService) lookupClient(res http.ResponseWriter, req *http.Request) (*types.Client, bool) {
+	client, err := auth.findClient(mux.Vars(req)["id"])
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if client == nil {
+		auth.render.JSON(res, http.StatusNotFound, "client not found")
+		return nil, false
+	}
+
+	return client, true
+}
+
+func (auth *AuthService) findClient(clientId string) (*types.Client, error) {
+	clients, err := dataservice.Get[*types.Client](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.Client{})],
+		Key:        "id",
+		Value:      clientId,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil || len(clients) == 0 {
+		return nil, err
+	}
+
+	return clients[0], nil
+}
+
+// setClientSecret generates and stores a new secret for the client, the secret is
+// returned so it can be handed to the caller once
+func (auth *AuthService) setClientSecret(client *types.Client) (string, error) {
+	secret, err := secure.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	client.SecretHash = secure.HashToken(secret)
+
+	return secret, auth.save(client)
+}
+
+/**********************************************************************************/
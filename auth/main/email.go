@@ -0,0 +1,85 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+var emailClient = &http.Client{Timeout: 10 * time.Second}
+
+/**********************************************************************************/
+
+// sendEmail sends the email to the user through the email service
+func (auth *AuthService) sendEmail(user *types.User, subject, body string) error {
+	if conf.Get().EmailService == "" {
+		return fmt.Errorf("the email service has not been configured")
+	}
+
+	email := &types.Email{
+		ToAddresses: []string{user.Email()},
+		FromAddress: conf.Get().EmailFrom,
+		Subject:     subject,
+		Body:        body,
+		User:        user.Username,
+	}
+
+	emailBytes, err := json.Marshal(email)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/kp/email/%s", strings.TrimSuffix(conf.Get().EmailService, "/"), url.PathEscape(user.Username))
+	response, err := emailClient.Post(endpoint, "application/json", bytes.NewReader(emailBytes))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("the email service returned status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+// linkWithToken appends the token as a query parameter to the link
+func linkWithToken(link, token string) string {
+	separator := "?"
+	if strings.Contains(link, "?") {
+		separator = "&"
+	}
+
+	return link + separator + "token=" + url.QueryEscape(token)
+}
+
+/**********************************************************************************/
@@ -0,0 +1,311 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+	logger "github.com/sirupsen/logrus"
+)
+
+const federationSessionKey = "federation"
+
+/**********************************************************************************/
+
+// getIdentityProviders returns the names of the identity providers users can log
+// in with
+func (auth *AuthService) getIdentityProviders(res http.ResponseWriter, req *http.Request) {
+	names := make([]string, 0, len(conf.Get().IdentityProviders))
+	for name := range conf.Get().IdentityProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	auth.render.JSON(res, http.StatusOK, names)
+}
+
+// federatedLogin redirects the user to the identity provider to log in, the
+// state, nonce and PKCE verifier of the login are kept in the session
+func (auth *AuthService) federatedLogin(res http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["provider"]
+	provider, ok := conf.Get().IdentityProviders[name]
+	if !ok {
+		auth.render.JSON(res, http.StatusNotFound, "identity provider not found")
+		return
+	}
+
+	returnTo := req.URL.Query().Get("return_to")
+	if returnTo != "" && !auth.allowedReturn(req, returnTo) {
+		auth.render.JSON(res, http.StatusBadRequest, "the return_to address is not allowed")
+		return
+	}
+
+	state := &types.FederationState{Provider: name, ReturnTo: returnTo}
+	for _, value := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+		token, err := secure.GenerateOpaqueToken(32)
+		if err != nil {
+			auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+		*value = token
+	}
+
+	authURL, err := secure.UpstreamAuthURL(&provider, auth.federationRedirect(req, name), state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		auth.render.JSON(res, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := secure.SetSessionValue(req, res, federationSessionKey, string(stateBytes)); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	http.Redirect(res, req, authURL, http.StatusFound)
+}
+
+// federatedCallback finishes a login with an identity provider. The id_token is
+// validated, the local user is found or created and our own tokens are issued
+func (auth *AuthService) federatedCallback(res http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["provider"]
+	provider, ok := conf.Get().IdentityProviders[name]
+	if !ok {
+		auth.render.JSON(res, http.StatusNotFound, "identity provider not found")
+		return
+	}
+
+	// The state is only good for one callback
+	stored, err := secure.GetSessionValue[string](req, federationSessionKey)
+	secure.SetSessionValue(req, res, federationSessionKey, "")
+
+	state := new(types.FederationState)
+	if err != nil || json.Unmarshal([]byte(stored), state) != nil ||
+		state.Provider != name || state.State == "" || state.State != req.URL.Query().Get("state") {
+		auth.render.JSON(res, http.StatusBadRequest, "the login state is not valid")
+		return
+	}
+
+	if upstreamError := req.URL.Query().Get("error"); upstreamError != "" {
+		auth.render.JSON(res, http.StatusUnauthorized, fmt.Sprintf("the identity provider refused the login: %s", upstreamError))
+		return
+	}
+
+	idToken, err := secure.ExchangeUpstreamCode(&provider, auth.federationRedirect(req, name), req.URL.Query().Get("code"), state.Verifier)
+	if err != nil {
+		auth.render.JSON(res, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	claims, err := secure.VerifyUpstreamIDToken(&provider, idToken, state.Nonce)
+	if err != nil {
+		auth.auditFailure(req, types.AuditLogin, "", name, "the id_token is not valid")
+		auth.render.JSON(res, http.StatusUnauthorized, fmt.Sprintf("the id_token is not valid: %s", err.Error()))
+		return
+	}
+
+	user, err := auth.federatedUser(name, &provider, claims)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// The local MFA policy still applies to federated logins
+	required, err := auth.mfaRequired(user)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if required || user.MFAEnabled() {
+		auth.issueChallenge(res, user)
+		return
+	}
+
+	auth.audit(req, types.AuditLogin, user.Id(), name)
+	token, ok := auth.startSession(res, req, user, "")
+	if !ok {
+		return
+	}
+
+	if state.ReturnTo != "" {
+		http.Redirect(res, req, state.ReturnTo, http.StatusFound)
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, token)
+}
+
+/**********************************************************************************/
+
+// federatedUser returns the local user linked to the provider's subject. A new
+// link is made to the user with the same email address when the provider is trusted
+// and has verified it, otherwise a new user is provisioned in the provider's
+// organization
+func (auth *AuthService) federatedUser(name string, provider *conf.IdentityProvider, claims jwt.MapClaims) (*types.User, error) {
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, errors.New("the id_token has no subject")
+	}
+
+	identity, err := auth.findFederatedIdentity(types.FederatedIdentityId(name, subject))
+	if err != nil {
+		return nil, err
+	}
+
+	if identity != nil {
+		user, err := auth.getUser(identity.Username)
+		if err != nil || user != nil {
+			identity.LastLogin = time.Now()
+			if err == nil {
+				err = auth.save(identity)
+			}
+
+			return user, err
+		}
+	}
+
+	// Only a trusted provider can link an existing account by its email address
+	var user *types.User
+	if email := trustedEmail(provider, claims); email != "" {
+		if user, err = auth.getUser(email); err != nil {
+			return nil, err
+		}
+	}
+
+	if user == nil {
+		if user, err = auth.provisionUser(name, provider, subject, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := auth.save(types.NewFederatedIdentity(name, subject, user.Id())); err != nil {
+		return nil, err
+	}
+	logger.Infof("linked %s user %s to %s", name, subject, user.Id())
+
+	return user, nil
+}
+
+// provisionUser creates the local user for a first login with the provider, the
+// user is given a random password they can replace with a password reset
+func (auth *AuthService) provisionUser(name string, provider *conf.IdentityProvider, subject string, claims jwt.MapClaims) (*types.User, error) {
+	if err := auth.validateOrg(provider.Organization); err != nil {
+		return nil, err
+	}
+
+	email, _ := claims["email"].(string)
+	givenName, _ := claims["given_name"].(string)
+	familyName, _ := claims["family_name"].(string)
+
+	// An email address from an untrusted provider can't claim the username
+	trusted := trustedEmail(provider, claims)
+	username := trusted
+	if username == "" {
+		username = types.FederatedIdentityId(name, subject)
+	}
+
+	password, err := secure.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	user := types.NewUser()
+	user.Username = username
+	user.Password = password
+	user.Organization = provider.Organization
+	user.EmailVerified = trusted != ""
+	user.Profile.FirstName = givenName
+	user.Profile.LastName = familyName
+	user.Profile.Email = email
+	if provider.Roles != nil {
+		user.Roles = provider.Roles
+	}
+
+	if err := auth.saveUser(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (auth *AuthService) findFederatedIdentity(id string) (*types.FederatedIdentity, error) {
+	identities, err := dataservice.Get[*types.FederatedIdentity](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.FederatedIdentity{})],
+		Key:        "id",
+		Value:      id,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil || len(identities) == 0 {
+		return nil, err
+	}
+
+	return identities[0], nil
+}
+
+// federationRedirect returns the callback address registered with the provider
+func (auth *AuthService) federationRedirect(req *http.Request, name string) string {
+	return fmt.Sprintf("%s/auth/federated/%s/callback", auth.issuer(req), name)
+}
+
+// allowedReturn only allows returning to a path of this service or an absolute
+// address on the issuer so the login can't be used as an open redirect
+func (auth *AuthService) allowedReturn(req *http.Request, returnTo string) bool {
+	if strings.HasPrefix(returnTo, "/") && !strings.HasPrefix(returnTo, "//") && !strings.HasPrefix(returnTo, "/\\") {
+		return true
+	}
+
+	return strings.HasPrefix(returnTo, auth.issuer(req)+"/")
+}
+
+// trustedEmail returns the email address of the id_token when the provider is
+// trusted to verify email addresses and has verified it
+func trustedEmail(provider *conf.IdentityProvider, claims jwt.MapClaims) string {
+	email, _ := claims["email"].(string)
+	verified, _ := claims["email_verified"].(bool)
+	if !provider.TrustEmail || !verified {
+		return ""
+	}
+
+	return email
+}
+
+/**********************************************************************************/
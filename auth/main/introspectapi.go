@@ -0,0 +1,151 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+// introspectionCacheTTL is how long an introspection result is reused, it is kept
+// short so revocations take effect quickly
+var introspectionCacheTTL = 10 * time.Second
+
+var (
+	introspectionCache = make(map[string]*introspectionEntry)
+	introspectionLock  sync.RWMutex
+	// introspectionCacheSize is the number of entries that triggers removing the
+	// entries that are no longer fresh
+	introspectionCacheSize = 10000
+)
+
+type introspectionEntry struct {
+	introspection *types.Introspection
+	checked       time.Time
+}
+
+/**********************************************************************************/
+
+// introspect is the token introspection endpoint (RFC 7662) for registered
+// confidential clients. Access tokens and API keys are introspected, anything else
+// is reported as not active
+func (auth *AuthService) introspect(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Cache-Control", "no-store")
+
+	if err := req.ParseForm(); err != nil {
+		auth.oauthError(res, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if _, ok := auth.authenticateClient(res, req, false); !ok {
+		return
+	}
+
+	token := req.PostForm.Get("token")
+	if token == "" {
+		auth.oauthError(res, http.StatusBadRequest, "invalid_request", "the token is required")
+		return
+	}
+
+	introspection, err := auth.introspectToken(token)
+	if err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, introspection)
+}
+
+/**********************************************************************************/
+
+// introspectToken returns the cached introspection of the token while it is fresh,
+// a cached active token that has since expired is reported as not active
+func (auth *AuthService) introspectToken(token string) (*types.Introspection, error) {
+	key := secure.HashToken(token)
+
+	introspectionLock.RLock()
+	entry, ok := introspectionCache[key]
+	introspectionLock.RUnlock()
+
+	if ok && time.Since(entry.checked) < introspectionCacheTTL {
+		if entry.introspection.Expires > 0 && time.Now().Unix() >= entry.introspection.Expires {
+			return &types.Introspection{Active: false}, nil
+		}
+
+		return entry.introspection, nil
+	}
+
+	introspection, err := auth.checkToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	introspectionLock.Lock()
+	if len(introspectionCache) >= introspectionCacheSize {
+		for cacheKey, cached := range introspectionCache {
+			if time.Since(cached.checked) >= introspectionCacheTTL {
+				delete(introspectionCache, cacheKey)
+			}
+		}
+	}
+	introspectionCache[key] = &introspectionEntry{introspection: introspection, checked: time.Now()}
+	introspectionLock.Unlock()
+
+	return introspection, nil
+}
+
+// checkToken verifies an access token or API key and checks it hasn't been revoked
+func (auth *AuthService) checkToken(token string) (*types.Introspection, error) {
+	inactive := &types.Introspection{Active: false}
+
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		claims, err := auth.resolveAPIKey(token)
+		if err != nil || claims == nil {
+			return inactive, err
+		}
+
+		return types.NewIntrospection(claims), nil
+	}
+
+	claims, err := secure.ParseAccessToken(token)
+	if err != nil {
+		return inactive, nil
+	}
+
+	revoked, err := secure.IsRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return inactive, nil
+	}
+
+	return types.NewIntrospection(claims), nil
+}
+
+/**********************************************************************************/
@@ -0,0 +1,81 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+/**********************************************************************************/
+
+func (auth *AuthService) getLockouts(res http.ResponseWriter, req *http.Request) {
+	attempts, err := secure.GetAllLoginAttempts()
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Only report keys that currently have failures or a lockout
+	found := make([]*types.LoginAttempts, 0)
+	for _, attempt := range attempts {
+		if attempt.Failures > 0 || attempt.IsLocked() {
+			found = append(found, attempt)
+		}
+	}
+
+	auth.render.JSON(res, http.StatusOK, found)
+}
+
+func (auth *AuthService) getLockout(res http.ResponseWriter, req *http.Request) {
+	attempts, err := secure.GetLoginAttempts(mux.Vars(req)["key"])
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if attempts == nil {
+		auth.render.JSON(res, http.StatusNotFound, "no failed logins found")
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, attempts)
+}
+
+func (auth *AuthService) unlock(res http.ResponseWriter, req *http.Request) {
+	key := mux.Vars(req)["key"]
+
+	if err := secure.ClearLoginFailures(key); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditUnlock, middleware.Subject(req), key)
+	auth.render.JSON(res, http.StatusOK, fmt.Sprintf("successfully unlocked %s", key))
+}
+
+/**********************************************************************************/
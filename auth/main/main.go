@@ -68,6 +68,14 @@ func main() {
 		"PID":        os.Getpid(),
 	}).Infof("Runtime configuration")
 
+	// The seed subcommand initializes the service and exits
+	if flag.Arg(0) == "seed" {
+		if err := runSeed(flag.Args()[1:]); err != nil {
+			logger.Fatalf("failed to seed the service: %s", err.Error())
+		}
+		return
+	}
+
 	authService, _ := NewAuthService(sessionName)
 	if err := authService.Start(); err != nil {
 		panic(err)
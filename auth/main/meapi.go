@@ -0,0 +1,177 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+/**********************************************************************************/
+
+// getMe returns the account of the caller
+func (auth *AuthService) getMe(res http.ResponseWriter, req *http.Request) {
+	user, ok := auth.currentUser(res, req)
+	if !ok {
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, types.NewUserInfo(user))
+}
+
+// patchMe changes the profile of the caller, the fields that are not sent,
+// including the fields of the address, are left unchanged
+func (auth *AuthService) patchMe(res http.ResponseWriter, req *http.Request) {
+	user, ok := auth.currentUser(res, req)
+	if !ok {
+		return
+	}
+
+	profile := new(types.UserProfile)
+	if user.Profile != nil {
+		*profile = *user.Profile
+	}
+	if err := json.NewDecoder(req.Body).Decode(profile); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	auth.updateUser(res, req, user, &types.UserPatch{Profile: profile})
+}
+
+// changeMyPassword changes the password of the caller, the current password must
+// be provided and wrong guesses count towards the login lockout
+func (auth *AuthService) changeMyPassword(res http.ResponseWriter, req *http.Request) {
+	user, ok := auth.currentUser(res, req)
+	if !ok {
+		return
+	}
+
+	change := new(types.PasswordChange)
+	if err := json.NewDecoder(req.Body).Decode(change); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	address := secure.ClientAddress(req)
+	locked, err := secure.LockedOut(user.Id(), address)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if locked > 0 {
+		res.Header().Set("Retry-After", strconv.Itoa(int(locked.Seconds())+1))
+		auth.render.JSON(res, http.StatusTooManyRequests, "too many failed attempts, try again later")
+		return
+	}
+
+	if !secure.CheckPasswordHash(change.Current, user.Password) {
+		if err := secure.RecordLoginFailure(user.Id(), address); err != nil {
+			logger.Errorf("failed to record the failed password check for %s: %s", user.Id(), err.Error())
+		}
+
+		auth.auditFailure(req, types.AuditPasswordChange, user.Id(), user.Id(), "incorrect current password")
+		auth.render.JSON(res, http.StatusUnauthorized, "the current password is incorrect")
+		return
+	}
+
+	if err := secure.ClearLoginFailures(types.UserAttemptsKey(user.Id())); err != nil {
+		logger.Errorf("failed to clear the failed logins for %s: %s", user.Id(), err.Error())
+	}
+
+	auth.changePassword(res, req, user, change.Password, types.AuditPasswordChange)
+}
+
+// deleteMe removes the account of the caller when the service allows users to
+// delete their own account, administrators are removed by another administrator
+func (auth *AuthService) deleteMe(res http.ResponseWriter, req *http.Request) {
+	if !conf.Get().SelfDelete {
+		auth.render.JSON(res, http.StatusForbidden, "accounts can only be deleted by an administrator")
+		return
+	}
+
+	user, ok := auth.currentUser(res, req)
+	if !ok {
+		return
+	}
+
+	if middleware.HasRole(req, adminRole) {
+		auth.render.JSON(res, http.StatusForbidden, "administrators can't delete their own account")
+		return
+	}
+
+	if err := auth.remove(user); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auth.revokeSubject(user.Id(), "user deleted"); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := auth.removeAPIKeys(user.Id()); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := secure.ClearSession(req, res); err != nil {
+		logger.Errorf("failed to clear the session of %s: %s", user.Id(), err.Error())
+	}
+	auth.expireRefreshCookie(res)
+
+	auth.audit(req, types.AuditUserDelete, user.Id(), user.Id())
+	auth.render.JSON(res, http.StatusOK, "successfully deleted the account")
+}
+
+/**********************************************************************************/
+
+// currentUser retrieves the user the caller authenticated as, rendering the error
+// response if the caller isn't a user
+func (auth *AuthService) currentUser(res http.ResponseWriter, req *http.Request) (*types.User, bool) {
+	principal := middleware.PrincipalFrom(req.Context())
+	if principal == nil || principal.IsClient() {
+		auth.render.JSON(res, http.StatusNotFound, "user not found")
+		return nil, false
+	}
+
+	user, err := auth.getUser(principal.Subject)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if user == nil {
+		auth.render.JSON(res, http.StatusNotFound, "user not found")
+		return nil, false
+	}
+
+	return user, true
+}
+
+/**********************************************************************************/
@@ -0,0 +1,426 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// mfaLocks serializes the MFA requests of each user so that a challenge or a code
+// can't be used by two requests at once
+var mfaLocks = secure.NewKeyLocks()
+
+/**********************************************************************************/
+
+// completeMFA finishes a login that is waiting on a second factor, the challenge
+// token is exchanged with a TOTP or recovery code for the access and refresh tokens
+func (auth *AuthService) completeMFA(res http.ResponseWriter, req *http.Request) {
+	verification := new(types.MFAVerification)
+	if err := json.NewDecoder(req.Body).Decode(verification); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	claims, unlock, err := auth.parseChallenge(verification.Challenge)
+	if err != nil {
+		auth.render.JSON(res, http.StatusUnauthorized, err.Error())
+		return
+	}
+	defer unlock()
+	subject, _ := claims.GetSubject()
+
+	if auth.mfaLockedOut(res, req, subject) {
+		return
+	}
+
+	user, err := auth.getUser(subject)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if user == nil {
+		auth.render.JSON(res, http.StatusUnauthorized, "the challenge is not valid")
+		return
+	}
+	if !user.MFAEnabled() {
+		auth.render.JSON(res, http.StatusForbidden, "multi-factor authentication must be enrolled to finish the login")
+		return
+	}
+
+	if !auth.checkMFACode(user, verification.Code) {
+		auth.recordMFAFailure(req, subject)
+		auth.auditFailure(req, types.AuditLogin, subject, subject, "incorrect mfa code")
+
+		auth.render.JSON(res, http.StatusUnauthorized, "the code is incorrect")
+		return
+	}
+
+	// The code was used so the user is saved even if the tokens can't be issued
+	if err := auth.save(user); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// A challenge can only finish one login
+	jti, _ := claims["jti"].(string)
+	expires, _ := claims.GetExpirationTime()
+	if err := secure.RevokeToken(jti, subject, expires.Time, "mfa challenge used"); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := secure.ClearLoginFailures(types.UserAttemptsKey(subject)); err != nil {
+		logger.Errorf("failed to clear the failed logins for %s: %s", subject, err.Error())
+	}
+
+	auth.audit(req, types.AuditLogin, user.Id(), user.Id())
+	auth.issueTokens(res, req, user, "")
+}
+
+// enrollMFA starts the enrollment by generating a pending secret for the caller,
+// the caller is identified by an access token or by an enrollment challenge
+func (auth *AuthService) enrollMFA(res http.ResponseWriter, req *http.Request) {
+	user, _, unlock, ok := auth.mfaUser(res, req)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	if user.MFAEnabled() {
+		auth.render.JSON(res, http.StatusConflict, "multi-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := secure.GenerateTOTPSecret()
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user.MFA = &types.MFASettings{PendingSecret: secret}
+	if err := auth.save(user); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	issuer := conf.Get().MFAIssuer
+	if issuer == "" {
+		issuer = "auth"
+	}
+
+	auth.render.JSON(res, http.StatusOK, &types.MFAEnrollment{
+		Secret: secret,
+		URI:    secure.TOTPURI(issuer, user.Username, secret),
+	})
+}
+
+// confirmMFA enables MFA once the first code from the pending secret is confirmed,
+// the recovery codes are returned once and only their hashes are kept
+func (auth *AuthService) confirmMFA(res http.ResponseWriter, req *http.Request) {
+	user, code, unlock, ok := auth.mfaUser(res, req)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	if auth.mfaLockedOut(res, req, user.Id()) {
+		return
+	}
+
+	if user.MFA == nil || user.MFA.PendingSecret == "" {
+		auth.render.JSON(res, http.StatusBadRequest, "multi-factor authentication enrollment has not been started")
+		return
+	}
+
+	step, valid := secure.ValidateTOTP(user.MFA.PendingSecret, code, 0)
+	if !valid {
+		auth.recordMFAFailure(req, user.Id())
+		auth.render.JSON(res, http.StatusBadRequest, "the code is incorrect")
+		return
+	}
+
+	codes, hashes, err := secure.GenerateRecoveryCodes()
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user.MFA = &types.MFASettings{
+		Secret:        user.MFA.PendingSecret,
+		RecoveryCodes: hashes,
+		LastStep:      step,
+		Enabled:       true,
+	}
+	if err := auth.save(user); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, codes)
+}
+
+// disableMFA turns off MFA for the caller after checking a current code, users
+// holding a role that requires MFA can't turn it off
+func (auth *AuthService) disableMFA(res http.ResponseWriter, req *http.Request) {
+	user, code, unlock, ok := auth.mfaUser(res, req)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	if auth.mfaLockedOut(res, req, user.Id()) {
+		return
+	}
+
+	if !user.MFAEnabled() {
+		auth.render.JSON(res, http.StatusBadRequest, "multi-factor authentication is not enabled")
+		return
+	}
+
+	required, err := auth.mfaRequired(user)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if required {
+		auth.render.JSON(res, http.StatusForbidden, "multi-factor authentication is required by the user's roles")
+		return
+	}
+
+	if !auth.checkMFACode(user, code) {
+		auth.recordMFAFailure(req, user.Id())
+		auth.render.JSON(res, http.StatusUnauthorized, "the code is incorrect")
+		return
+	}
+
+	user.MFA = nil
+	if err := auth.save(user); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, "multi-factor authentication has been disabled")
+}
+
+// resetMFA removes the MFA settings of a user that has lost their authenticator
+// and recovery codes, the user has to enroll again at their next login if a role
+// requires it
+func (auth *AuthService) resetMFA(res http.ResponseWriter, req *http.Request) {
+	user, ok := auth.lookupUser(res, req)
+	if !ok {
+		return
+	}
+
+	user.MFA = nil
+	if err := auth.save(user); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auth.revokeSubject(user.Id(), "mfa reset"); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, types.NewUserInfo(user))
+}
+
+/**********************************************************************************/
+
+// issueChallenge returns the challenge token for a login waiting on a second
+// factor, users that haven't enrolled get a challenge that allows enrollment
+func (auth *AuthService) issueChallenge(res http.ResponseWriter, user *types.User) {
+	key, err := secure.GetSigningKey(secure.MFAKeyName)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	enroll := !user.MFAEnabled()
+	challenge, err := secure.GenerateMFAJWT(key, user, enroll)
+	if err != nil {
+		auth.render.JSON(res, http.StatusUnauthorized, "failed to generate challenge")
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, &types.MFAChallenge{
+		Challenge:   challenge,
+		MFARequired: true,
+		Enroll:      enroll,
+	})
+}
+
+// parseChallenge validates the challenge token and checks it hasn't been used. The
+// user of the challenge is locked until the returned function is called, so the
+// challenge is only checked once no other request can be using it
+func (auth *AuthService) parseChallenge(challenge string) (jwt.MapClaims, func(), error) {
+	claims, err := secure.ParseJWT(secure.MFAKeyName, challenge)
+	if err != nil {
+		return nil, nil, errors.New("the challenge is not valid")
+	}
+
+	subject, _ := claims.GetSubject()
+	unlock := mfaLocks.Lock(subject)
+
+	revoked, err := secure.IsRevoked(claims)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	if revoked {
+		unlock()
+		return nil, nil, errors.New("the challenge has already been used")
+	}
+
+	return claims, unlock, nil
+}
+
+// mfaUser returns the user making an MFA request along with the code in the body.
+// An enrollment challenge identifies the user part way through a login, otherwise
+// the caller must present an access token. The user is locked until the returned
+// function is called
+func (auth *AuthService) mfaUser(res http.ResponseWriter, req *http.Request) (*types.User, string, func(), bool) {
+	verification := new(types.MFAVerification)
+	if err := json.NewDecoder(req.Body).Decode(verification); err != nil && !errors.Is(err, io.EOF) {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return nil, "", nil, false
+	}
+
+	var subject string
+	var unlock func()
+	if verification.Challenge != "" {
+		claims, challengeUnlock, err := auth.parseChallenge(verification.Challenge)
+		if err != nil {
+			auth.render.JSON(res, http.StatusUnauthorized, err.Error())
+			return nil, "", nil, false
+		}
+		unlock = challengeUnlock
+
+		if enroll, _ := claims["enroll"].(bool); !enroll {
+			unlock()
+			auth.render.JSON(res, http.StatusUnauthorized, "the challenge does not allow enrollment")
+			return nil, "", nil, false
+		}
+
+		subject, _ = claims.GetSubject()
+	} else {
+		claims, err := secure.ParseAccessToken(middleware.TokenFromRequest(req))
+		if err != nil {
+			auth.render.JSON(res, http.StatusUnauthorized, err.Error())
+			return nil, "", nil, false
+		}
+
+		if revoked, err := secure.IsRevoked(claims); err != nil || revoked {
+			auth.render.JSON(res, http.StatusUnauthorized, "token has been revoked")
+			return nil, "", nil, false
+		}
+
+		subject, _ = claims.GetSubject()
+		unlock = mfaLocks.Lock(subject)
+	}
+
+	user, err := auth.getUser(subject)
+	if err != nil {
+		unlock()
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return nil, "", nil, false
+	}
+	if user == nil {
+		unlock()
+		auth.render.JSON(res, http.StatusNotFound, "user not found")
+		return nil, "", nil, false
+	}
+
+	return user, verification.Code, unlock, true
+}
+
+// mfaLockedOut refuses the request when the user or the client address is locked
+// out, wrong codes count towards the same lockout as wrong passwords
+func (auth *AuthService) mfaLockedOut(res http.ResponseWriter, req *http.Request, subject string) bool {
+	locked, err := secure.LockedOut(subject, secure.ClientAddress(req))
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return true
+	}
+	if locked > 0 {
+		res.Header().Set("Retry-After", strconv.Itoa(int(locked.Seconds())+1))
+		auth.render.JSON(res, http.StatusTooManyRequests, "too many failed login attempts, try again later")
+		return true
+	}
+
+	return false
+}
+
+// recordMFAFailure counts a wrong code against the user and the client address
+func (auth *AuthService) recordMFAFailure(req *http.Request, subject string) {
+	if err := secure.RecordLoginFailure(subject, secure.ClientAddress(req)); err != nil {
+		logger.Errorf("failed to record the failed login for %s: %s", subject, err.Error())
+	}
+}
+
+// mfaRequired returns true if any of the user's active roles requires MFA
+func (auth *AuthService) mfaRequired(user *types.User) (bool, error) {
+	roles, err := auth.getRoleMap()
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range user.Roles {
+		if role, ok := roles[name]; ok && role.Active && role.RequireMFA {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkMFACode checks a TOTP code or a recovery code for the user. A used recovery
+// code is removed and the last TOTP time step is recorded, the caller saves the user
+func (auth *AuthService) checkMFACode(user *types.User, code string) bool {
+	if step, ok := secure.ValidateTOTP(user.MFA.Secret, code, user.MFA.LastStep); ok {
+		user.MFA.LastStep = step
+		return true
+	}
+
+	hash := secure.HashRecoveryCode(code)
+	if index := slices.Index(user.MFA.RecoveryCodes, hash); index >= 0 {
+		user.MFA.RecoveryCodes = slices.Delete(user.MFA.RecoveryCodes, index, index+1)
+		return true
+	}
+
+	return false
+}
+
+/**********************************************************************************/
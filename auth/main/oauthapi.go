@@ -0,0 +1,150 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+const (
+	grantClientCredentials = "client_credentials"
+	grantAuthorizationCode = "authorization_code"
+)
+
+// dummyClientSecretHash is checked for unknown clients
+var dummyClientSecretHash = secure.HashToken("not-a-real-client-secret")
+
+/**********************************************************************************/
+
+// oauthToken is the OAuth2 token endpoint (RFC 6749 section 3.2), clients
+// authenticate with HTTP basic authentication or with the client_id and
+// client_secret form parameters
+func (auth *AuthService) oauthToken(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Cache-Control", "no-store")
+
+	if err := req.ParseForm(); err != nil {
+		auth.oauthError(res, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	switch req.PostForm.Get("grant_type") {
+	case grantClientCredentials:
+		auth.clientCredentialsGrant(res, req)
+	case grantAuthorizationCode:
+		auth.authorizationCodeGrant(res, req)
+	case "":
+		auth.oauthError(res, http.StatusBadRequest, "invalid_request", "the grant_type is required")
+	default:
+		auth.oauthError(res, http.StatusBadRequest, "unsupported_grant_type", "")
+	}
+}
+
+/**********************************************************************************/
+
+func (auth *AuthService) clientCredentialsGrant(res http.ResponseWriter, req *http.Request) {
+	client, ok := auth.authenticateClient(res, req, false)
+	if !ok {
+		return
+	}
+
+	scopes, ok := client.GrantScopes(strings.Fields(req.PostForm.Get("scope")))
+	if !ok {
+		auth.oauthError(res, http.StatusBadRequest, "invalid_scope", "a requested scope is not allowed for the client")
+		return
+	}
+
+	key, err := secure.GetSigningKey(secure.AccessKeyName)
+	if err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	token, err := secure.GenerateClientJWT(key, client, scopes)
+	if err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", "failed to generate token")
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, &types.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(key.Expiry.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	})
+}
+
+// authenticateClient checks the client credentials of the request, the same error
+// is returned for an unknown client, a wrong secret and an inactive client. Public
+// clients only identify themselves and are refused unless allowPublic is set
+func (auth *AuthService) authenticateClient(res http.ResponseWriter, req *http.Request, allowPublic bool) (*types.Client, bool) {
+	clientId, clientSecret, ok := req.BasicAuth()
+	if !ok {
+		clientId = req.PostForm.Get("client_id")
+		clientSecret = req.PostForm.Get("client_secret")
+	}
+
+	client, err := auth.findClient(clientId)
+	if err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", err.Error())
+		return nil, false
+	}
+
+	if client != nil && client.Public {
+		if !allowPublic || !client.Active {
+			auth.oauthError(res, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+			return nil, false
+		}
+
+		return client, true
+	}
+
+	if !auth.checkClientSecret(client, clientSecret) || !client.Active {
+		res.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		auth.oauthError(res, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return nil, false
+	}
+
+	return client, true
+}
+
+// checkClientSecret checks the secret of the client against the stored hash, the
+// secrets are random so a fast hash is enough. An unknown client is checked
+// against a dummy hash so it takes as long as a wrong secret
+func (auth *AuthService) checkClientSecret(client *types.Client, secret string) bool {
+	if client == nil {
+		secure.CompareTokenHash(secret, dummyClientSecretHash)
+		return false
+	}
+
+	return secure.CompareTokenHash(secret, client.SecretHash)
+}
+
+func (auth *AuthService) oauthError(res http.ResponseWriter, status int, code, description string) {
+	auth.render.JSON(res, status, &types.OAuthError{Error: code, Description: description})
+}
+
+/**********************************************************************************/
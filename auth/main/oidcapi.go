@@ -0,0 +1,336 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+)
+
+var authorizationCodeExpiry = time.Minute
+
+/**********************************************************************************/
+
+// authorize is the OpenID Connect authorization endpoint, the user is identified
+// by the session of an earlier login and is sent to the login page when there is
+// none. Only the authorization code flow with S256 PKCE is supported
+func (auth *AuthService) authorize(res http.ResponseWriter, req *http.Request) {
+	request := parseAuthorizationRequest(req)
+
+	// Errors with the client or redirect URI are never redirected
+	client, err := auth.findClient(request.ClientID)
+	if err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	if client == nil || !client.Active {
+		auth.oauthError(res, http.StatusBadRequest, "invalid_request", "the client is not valid")
+		return
+	}
+	if !client.AllowsRedirect(request.RedirectURI) {
+		auth.oauthError(res, http.StatusBadRequest, "invalid_request", "the redirect_uri is not registered for the client")
+		return
+	}
+
+	if request.ResponseType != "code" {
+		auth.redirectError(res, req, request, "unsupported_response_type", "only the code response type is supported")
+		return
+	}
+	if !slices.Contains(request.Scopes, "openid") {
+		auth.redirectError(res, req, request, "invalid_scope", "the openid scope is required")
+		return
+	}
+	for _, scope := range request.Scopes {
+		if !slices.Contains(types.StandardScopes, scope) && !slices.Contains(client.Scopes, scope) {
+			auth.redirectError(res, req, request, "invalid_scope", "a requested scope is not allowed for the client")
+			return
+		}
+	}
+	if request.CodeChallenge == "" || request.CodeChallengeMethod != "S256" {
+		auth.redirectError(res, req, request, "invalid_request", "a S256 PKCE code_challenge is required")
+		return
+	}
+
+	user, authTime := auth.sessionUser(req)
+	if user == nil || request.Prompt == "login" {
+		loginURL := conf.Get().LoginURL
+		if request.Prompt == "none" || loginURL == "" {
+			auth.redirectError(res, req, request, "login_required", "the user is not logged in")
+			return
+		}
+
+		// The login page sends the user back to this request once they have logged in,
+		// without the prompt so that the new login is used
+		query := req.URL.Query()
+		query.Del("prompt")
+		returnTo := auth.issuer(req) + req.URL.Path + "?" + query.Encode()
+		http.Redirect(res, req, withParams(loginURL, url.Values{"return_to": {returnTo}}), http.StatusFound)
+		return
+	}
+
+	code, err := secure.GenerateOpaqueToken(32)
+	if err != nil {
+		auth.redirectError(res, req, request, "server_error", err.Error())
+		return
+	}
+
+	record := types.NewAuthorizationCode(secure.HashToken(code), request, user.Id(), authTime, authorizationCodeExpiry)
+	if err := auth.save(record); err != nil {
+		auth.redirectError(res, req, request, "server_error", err.Error())
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+
+	http.Redirect(res, req, withParams(request.RedirectURI, params), http.StatusFound)
+}
+
+// userinfo returns the standard claims of the caller released by the scopes of the
+// access token, tokens from a direct login release all of them
+func (auth *AuthService) userinfo(res http.ResponseWriter, req *http.Request) {
+	user, err := auth.getUser(middleware.Subject(req))
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if user == nil {
+		auth.oauthError(res, http.StatusUnauthorized, "invalid_token", "the token does not belong to a user")
+		return
+	}
+
+	scopes := middleware.Scopes(req)
+	if len(scopes) == 0 {
+		scopes = types.StandardScopes
+	}
+
+	auth.render.JSON(res, http.StatusOK, types.NewStandardClaims(user, scopes))
+}
+
+func (auth *AuthService) openidConfiguration(res http.ResponseWriter, req *http.Request) {
+	issuer := auth.issuer(req)
+
+	algorithms := make([]string, 0)
+	if key, err := secure.GetSigningKey(secure.AccessKeyName); err == nil {
+		algorithms = append(algorithms, key.Method.Alg())
+	}
+
+	auth.render.JSON(res, http.StatusOK, &types.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserInfoEndpoint:                  issuer + "/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		ScopesSupported:                   types.StandardScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{grantAuthorizationCode, grantClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algorithms,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "given_name",
+			"family_name", "preferred_username", "updated_at", "email", "email_verified", "phone_number", "address"},
+	})
+}
+
+/**********************************************************************************/
+
+// authorizationCodeGrant exchanges an authorization code and its PKCE verifier for
+// an access token and id_token, a code can only be exchanged once
+func (auth *AuthService) authorizationCodeGrant(res http.ResponseWriter, req *http.Request) {
+	client, ok := auth.authenticateClient(res, req, true)
+	if !ok {
+		return
+	}
+
+	record, err := auth.findAuthorizationCode(secure.HashToken(req.PostForm.Get("code")))
+	if err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	if record == nil || record.ClientID != client.Id() {
+		auth.oauthError(res, http.StatusBadRequest, "invalid_grant", "the code is not valid")
+		return
+	}
+
+	if err := auth.remove(record); err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	if record.IsExpired() || record.RedirectURI != req.PostForm.Get("redirect_uri") ||
+		!secure.VerifyCodeChallenge(req.PostForm.Get("code_verifier"), record.CodeChallenge) {
+		auth.oauthError(res, http.StatusBadRequest, "invalid_grant", "the code is not valid")
+		return
+	}
+
+	user, err := auth.getUser(record.Subject)
+	if err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	if user == nil {
+		auth.oauthError(res, http.StatusBadRequest, "invalid_grant", "the code is not valid")
+		return
+	}
+
+	key, err := secure.GetSigningKey(secure.AccessKeyName)
+	if err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	accessToken, err := secure.GenerateScopedJWT(key, user, client.Id(), record.Scopes)
+	if err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", "failed to generate token")
+		return
+	}
+
+	idToken, err := secure.GenerateIDToken(key, user, client.Id(), auth.issuer(req), record.Nonce, record.AuthTime,
+		types.NewStandardClaims(user, record.Scopes))
+	if err != nil {
+		auth.oauthError(res, http.StatusInternalServerError, "server_error", "failed to generate token")
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, &types.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(key.Expiry.Seconds()),
+		Scope:       strings.Join(record.Scopes, " "),
+		IDToken:     idToken,
+	})
+}
+
+func (auth *AuthService) findAuthorizationCode(codeHash string) (*types.AuthorizationCode, error) {
+	codes, err := dataservice.Get[*types.AuthorizationCode](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.AuthorizationCode{})],
+		Key:        "id",
+		Value:      codeHash,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil || len(codes) == 0 {
+		return nil, err
+	}
+
+	return codes[0], nil
+}
+
+// sessionUser returns the user logged in to the session along with the time they
+// logged in, nil is returned when there is no valid login
+func (auth *AuthService) sessionUser(req *http.Request) (*types.User, time.Time) {
+	claims, err := secure.ParseAccessToken(middleware.TokenFromRequest(req))
+	if err != nil {
+		return nil, time.Time{}
+	}
+
+	if revoked, err := secure.IsRevoked(claims); err != nil || revoked {
+		return nil, time.Time{}
+	}
+
+	subject, _ := claims.GetSubject()
+	user, err := auth.getUser(subject)
+	if err != nil || user == nil {
+		return nil, time.Time{}
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return user, time.Now()
+	}
+
+	return user, issuedAt.Time
+}
+
+// issuer returns the configured issuer, or the address the request was made to
+// when none is configured
+func (auth *AuthService) issuer(req *http.Request) string {
+	if issuer := conf.Get().Issuer; issuer != "" {
+		return strings.TrimSuffix(issuer, "/")
+	}
+
+	scheme := "http"
+	if req.TLS != nil || (conf.Get().TrustProxy && req.Header.Get("X-Forwarded-Proto") == "https") {
+		scheme = "https"
+	}
+
+	return scheme + "://" + req.Host
+}
+
+// redirectError sends an authorization error back to the client's redirect URI
+func (auth *AuthService) redirectError(res http.ResponseWriter, req *http.Request, request *types.AuthorizationRequest, code, description string) {
+	params := url.Values{"error": {code}, "error_description": {description}}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+
+	http.Redirect(res, req, withParams(request.RedirectURI, params), http.StatusFound)
+}
+
+func parseAuthorizationRequest(req *http.Request) *types.AuthorizationRequest {
+	query := req.URL.Query()
+
+	return &types.AuthorizationRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		State:               query.Get("state"),
+		Nonce:               query.Get("nonce"),
+		Prompt:              query.Get("prompt"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+		Scopes:              strings.Fields(query.Get("scope")),
+	}
+}
+
+// withParams adds the parameters to the query of the URL
+func withParams(target string, params url.Values) string {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	query := parsed.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+/**********************************************************************************/
@@ -0,0 +1,263 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+)
+
+/**********************************************************************************/
+
+func (auth *AuthService) getOrgs(res http.ResponseWriter, req *http.Request) {
+	orgs, err := dataservice.Get[*types.Organization](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.Organization{})],
+		Key:        "type",
+		Value:      util.GetTypeName(types.Organization{}),
+		Comparator: dsapi.EQ,
+	})
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, orgs)
+}
+
+func (auth *AuthService) getOrg(res http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+
+	// Callers can only see their own organization unless they are an admin
+	if !middleware.HasRole(req, adminRole) && middleware.Org(req) != name {
+		auth.render.JSON(res, http.StatusForbidden, "forbidden: not a member of the organization")
+		return
+	}
+
+	org, err := auth.findOrg(name)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if org == nil {
+		auth.render.JSON(res, http.StatusNotFound, "organization not found")
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, org)
+}
+
+func (auth *AuthService) addOrg(res http.ResponseWriter, req *http.Request) {
+	org := new(types.Organization)
+	if err := json.NewDecoder(req.Body).Decode(org); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+	org.Name = mux.Vars(req)["name"]
+	org.Created = time.Now()
+
+	existing, err := auth.findOrg(org.Name)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if existing != nil {
+		auth.render.JSON(res, http.StatusConflict, fmt.Sprintf("organization %s already exists", org.Name))
+		return
+	}
+
+	if err := auth.save(org); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditOrgCreate, middleware.Subject(req), org.Name)
+	auth.render.JSON(res, http.StatusCreated, org)
+}
+
+func (auth *AuthService) updateOrg(res http.ResponseWriter, req *http.Request) {
+	org := new(types.Organization)
+	if err := json.NewDecoder(req.Body).Decode(org); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+	org.Name = mux.Vars(req)["name"]
+
+	existing, err := auth.findOrg(org.Name)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if existing == nil {
+		auth.render.JSON(res, http.StatusNotFound, "organization not found")
+		return
+	}
+	org.Created = existing.Created
+
+	if err := auth.save(org); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditOrgUpdate, middleware.Subject(req), org.Name)
+	auth.render.JSON(res, http.StatusOK, org)
+}
+
+func (auth *AuthService) deleteOrg(res http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+
+	org, err := auth.findOrg(name)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if org == nil {
+		auth.render.JSON(res, http.StatusNotFound, "organization not found")
+		return
+	}
+
+	// Every user must belong to an organization, members have to be moved first
+	members, err := auth.getOrgMembers(name)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(members) > 0 {
+		auth.render.JSON(res, http.StatusConflict, fmt.Sprintf("organization %s still has %d members", name, len(members)))
+		return
+	}
+
+	if err := auth.remove(org); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditOrgDelete, middleware.Subject(req), name)
+	auth.render.JSON(res, http.StatusOK, fmt.Sprintf("successfully deleted organization %s", name))
+}
+
+func (auth *AuthService) getMembers(res http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+
+	if !middleware.HasRole(req, adminRole) && middleware.Org(req) != name {
+		auth.render.JSON(res, http.StatusForbidden, "forbidden: not a member of the organization")
+		return
+	}
+
+	members, err := auth.getOrgMembers(name)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, types.NewUserInfoList(members))
+}
+
+// addMember moves the user into the organization, a user belongs to exactly one
+// organization so this replaces any previous membership
+func (auth *AuthService) addMember(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+
+	org, err := auth.findOrg(vars["name"])
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if org == nil {
+		auth.render.JSON(res, http.StatusNotFound, "organization not found")
+		return
+	}
+	if !org.Active {
+		auth.render.JSON(res, http.StatusBadRequest, fmt.Sprintf("organization %s is not active", org.Name))
+		return
+	}
+
+	user, err := auth.getUser(vars["username"])
+	if err != nil || user == nil {
+		auth.render.JSON(res, http.StatusNotFound, "user not found")
+		return
+	}
+
+	user.Organization = org.Name
+	if err := auth.save(user); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditOrgMember, middleware.Subject(req), user.Id())
+	auth.render.JSON(res, http.StatusOK, fmt.Sprintf("successfully added %s to organization %s", user.Username, org.Name))
+}
+
+/**********************************************************************************/
+
+func (auth *AuthService) findOrg(name string) (*types.Organization, error) {
+	orgs, err := dataservice.Get[*types.Organization](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.Organization{})],
+		Key:        "id",
+		Value:      name,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil || len(orgs) == 0 {
+		return nil, err
+	}
+
+	return orgs[0], nil
+}
+
+func (auth *AuthService) getOrgMembers(name string) ([]*types.User, error) {
+	return dataservice.Get[*types.User](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.User{})],
+		Key:        "org",
+		Value:      name,
+		Comparator: dsapi.EQ,
+	})
+}
+
+// validateOrg checks that the organization a user is assigned to exists and is
+// active
+func (auth *AuthService) validateOrg(name string) error {
+	if name == "" {
+		return fmt.Errorf("the user must belong to an organization")
+	}
+
+	org, err := auth.findOrg(name)
+	if err != nil {
+		return err
+	}
+	if org == nil || !org.Active {
+		return fmt.Errorf("organization %s does not exist or is not active", name)
+	}
+
+	return nil
+}
+
+/**********************************************************************************/
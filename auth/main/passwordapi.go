@@ -0,0 +1,189 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+	logger "github.com/sirupsen/logrus"
+)
+
+var (
+	defaultResetExpiry = 30 * time.Minute
+
+	// resetLocks serializes the use of each reset token so that checking and
+	// consuming the token can't interleave
+	resetLocks = secure.NewKeyLocks()
+)
+
+/**********************************************************************************/
+
+// forgotPassword sends a password reset link to the user. The response is the same
+// whether or not the user exists so accounts can't be discovered
+func (auth *AuthService) forgotPassword(res http.ResponseWriter, req *http.Request) {
+	resetRequest := new(types.PasswordResetRequest)
+	if err := json.NewDecoder(req.Body).Decode(resetRequest); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The link is sent in the background so the response time doesn't reveal
+	// whether the user exists
+	go func() {
+		if err := auth.sendResetLink(resetRequest.Username); err != nil {
+			logger.Errorf("failed to send the password reset link for %s: %s", resetRequest.Username, err.Error())
+		}
+	}()
+
+	auth.render.JSON(res, http.StatusOK, "if the account exists a password reset link has been sent")
+}
+
+// resetPassword consumes the reset token and sets the new password, every token
+// issued to the user is revoked along with the user's other reset tokens
+func (auth *AuthService) resetPassword(res http.ResponseWriter, req *http.Request) {
+	reset := new(types.PasswordReset)
+	if err := json.NewDecoder(req.Body).Decode(reset); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reset.Password == "" {
+		auth.render.JSON(res, http.StatusBadRequest, "the password is required")
+		return
+	}
+
+	tokenHash := secure.HashToken(reset.Token)
+	unlock := resetLocks.Lock(tokenHash)
+	defer unlock()
+
+	resetTokens, err := dataservice.Get[*types.ResetToken](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.ResetToken{})],
+		Key:        "id",
+		Value:      tokenHash,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(resetTokens) == 0 || !resetTokens[0].IsValid() {
+		auth.auditFailure(req, types.AuditPasswordReset, "", "", "invalid reset token")
+		auth.render.JSON(res, http.StatusBadRequest, "the password reset token is not valid")
+		return
+	}
+	resetToken := resetTokens[0]
+
+	user, err := auth.getUser(resetToken.Username)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if user == nil {
+		auth.render.JSON(res, http.StatusBadRequest, "the password reset token is not valid")
+		return
+	}
+
+	// The token is consumed before the password is changed so it can never be
+	// used twice
+	resetToken.Used = time.Now()
+	if err := auth.save(resetToken); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := auth.expireResetTokens(user.Id()); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := secure.ClearLoginFailures(types.UserAttemptsKey(user.Id())); err != nil {
+		logger.Errorf("failed to clear the failed logins for %s: %s", user.Id(), err.Error())
+	}
+
+	auth.changePassword(res, req, user, reset.Password, types.AuditPasswordReset)
+}
+
+/**********************************************************************************/
+
+func (auth *AuthService) sendResetLink(username string) error {
+	user, err := auth.getUser(username)
+	if err != nil || user == nil {
+		return err
+	}
+
+	token, err := secure.GenerateOpaqueToken(32)
+	if err != nil {
+		return err
+	}
+
+	expiry := conf.Get().ResetExpiry
+	if expiry <= 0 {
+		expiry = defaultResetExpiry
+	}
+
+	if err := auth.save(types.NewResetToken(secure.HashToken(token), user.Id(), expiry)); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("A password reset was requested for your account. Use the link below within %s to choose a new password.\n\n%s\n\nIf you did not request a reset you can ignore this email.",
+		expiry.String(), linkWithToken(conf.Get().ResetURL, token))
+
+	return auth.sendEmail(user, "Reset your password", body)
+}
+
+// expireResetTokens marks every reset token of the user that can still be used as
+// used
+func (auth *AuthService) expireResetTokens(username string) error {
+	resetTokens, err := dataservice.Get[*types.ResetToken](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.ResetToken{})],
+		Key:        "username",
+		Value:      username,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, resetToken := range resetTokens {
+		if !resetToken.IsValid() {
+			continue
+		}
+
+		resetToken.Used = time.Now()
+		if err := auth.save(resetToken); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**********************************************************************************/
@@ -0,0 +1,226 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+)
+
+/**********************************************************************************/
+
+func (auth *AuthService) getRole(res http.ResponseWriter, req *http.Request) {
+	role, err := auth.findRole(mux.Vars(req)["name"])
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if role == nil {
+		auth.render.JSON(res, http.StatusNotFound, "role not found")
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, role)
+}
+
+func (auth *AuthService) addRole(res http.ResponseWriter, req *http.Request) {
+	role := new(types.Role)
+	if err := json.NewDecoder(req.Body).Decode(role); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+	role.Name = mux.Vars(req)["name"]
+	role.Created = time.Now()
+
+	roles, err := auth.getRoleMap()
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if _, ok := roles[role.Name]; ok {
+		auth.render.JSON(res, http.StatusConflict, fmt.Sprintf("role %s already exists", role.Name))
+		return
+	}
+
+	if err := validateRole(roles, role); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := auth.save(role); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditRoleCreate, middleware.Subject(req), role.Name)
+	auth.render.JSON(res, http.StatusCreated, role)
+}
+
+func (auth *AuthService) updateRole(res http.ResponseWriter, req *http.Request) {
+	role := new(types.Role)
+	if err := json.NewDecoder(req.Body).Decode(role); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+	role.Name = mux.Vars(req)["name"]
+
+	roles, err := auth.getRoleMap()
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	existing, ok := roles[role.Name]
+	if !ok {
+		auth.render.JSON(res, http.StatusNotFound, "role not found")
+		return
+	}
+	role.Created = existing.Created
+
+	if err := validateRole(roles, role); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := auth.save(role); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditRoleUpdate, middleware.Subject(req), role.Name)
+	auth.render.JSON(res, http.StatusOK, role)
+}
+
+func (auth *AuthService) deleteRole(res http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+
+	roles, err := auth.getRoleMap()
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	role, ok := roles[name]
+	if !ok {
+		auth.render.JSON(res, http.StatusNotFound, "role not found")
+		return
+	}
+
+	// Roles that other roles inherit from can't be removed without breaking the
+	// hierarchy
+	for _, other := range roles {
+		if other.Name != name && slices.Contains(other.Parents, name) {
+			auth.render.JSON(res, http.StatusConflict, fmt.Sprintf("role %s is a parent of role %s", name, other.Name))
+			return
+		}
+	}
+
+	if err := auth.remove(role); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditRoleDelete, middleware.Subject(req), name)
+	auth.render.JSON(res, http.StatusOK, fmt.Sprintf("successfully deleted role %s", name))
+}
+
+/**********************************************************************************/
+
+func (auth *AuthService) findRole(name string) (*types.Role, error) {
+	roles, err := dataservice.Get[*types.Role](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.Role{})],
+		Key:        "id",
+		Value:      name,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil || len(roles) == 0 {
+		return nil, err
+	}
+
+	return roles[0], nil
+}
+
+// getRoleMap returns every role keyed by the role name
+func (auth *AuthService) getRoleMap() (map[string]*types.Role, error) {
+	roles, err := dataservice.Get[*types.Role](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.Role{})],
+		Key:        "type",
+		Value:      util.GetTypeName(types.Role{}),
+		Comparator: dsapi.EQ,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	roleMap := make(map[string]*types.Role)
+	for _, role := range roles {
+		roleMap[role.Name] = role
+	}
+
+	return roleMap, nil
+}
+
+// getPermissions returns the active roles of the user, including the roles they
+// inherit, and the effective permissions granted by them
+func (auth *AuthService) getPermissions(user *types.User) ([]string, []string, error) {
+	roles, err := auth.getRoleMap()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return types.ActiveRoles(roles, user.Roles), types.ResolvePermissions(roles, user.Roles), nil
+}
+
+// validateRole checks that the parents of the role exist and that the role does
+// not end up inheriting from itself
+func validateRole(roles map[string]*types.Role, role *types.Role) error {
+	if role.Name == "" {
+		return fmt.Errorf("the role name is required")
+	}
+
+	for _, parent := range role.Parents {
+		if _, ok := roles[parent]; !ok {
+			return fmt.Errorf("parent role %s does not exist", parent)
+		}
+	}
+
+	if types.InheritsFrom(roles, role, role.Name) {
+		return fmt.Errorf("role %s can't inherit from itself", role.Name)
+	}
+
+	return nil
+}
+
+/**********************************************************************************/
@@ -0,0 +1,260 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+	logger "github.com/sirupsen/logrus"
+)
+
+const (
+	initMethodHTTP     = "http"
+	initMethodSeed     = "seed"
+	initMethodMigrated = "migrated"
+
+	// initMarkerFile is the file earlier versions checked to refuse /init
+	initMarkerFile = "auth.init"
+)
+
+// initLock keeps concurrent HTTP initializations from both succeeding
+var initLock sync.Mutex
+
+/**********************************************************************************/
+
+// runSeed is the seed subcommand, it initializes the service from an enrollment
+// file without starting the API:
+//
+//	auth seed --file enrollment.json
+//
+// Seeding can be run again safely, only the records that don't exist are created
+func runSeed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	file := flags.String("file", "enrollment.json", "specifies the enrollment file used to seed the service")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	fileBytes, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+
+	enrollment := new(types.Enrollment)
+	if err := json.Unmarshal(fileBytes, enrollment); err != nil {
+		return fmt.Errorf("failed to parse %s: %s", *file, err.Error())
+	}
+
+	if err := checkSecrets(enrollment); err != nil {
+		return err
+	}
+
+	// The supplied secrets are stored before the service loads its keys so that it
+	// doesn't generate its own, the key grace period is set first as importing
+	// registers the keys
+	secure.SetKeyGracePeriod(conf.Get().KeyGrace)
+	if err := importMissingSecrets(enrollment); err != nil {
+		return err
+	}
+
+	authService, err := NewAuthService(sessionName)
+	if err != nil {
+		return err
+	}
+
+	user, err := authService.enroll(enrollment)
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		logger.Infof("created user %s", user.Id())
+	}
+
+	state, err := authService.getInitState()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		if err := authService.save(types.NewInitState(initMethodSeed, enrollment.User.Username)); err != nil {
+			return err
+		}
+		if err := authService.save(types.NewAuditEvent(types.AuditInit, enrollment.User.Username, initMethodSeed, types.AuditSuccess, "")); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("the service has been seeded")
+	return nil
+}
+
+// importMissingSecrets stores the supplied secrets the service doesn't have yet,
+// existing secrets are kept so that seeding again doesn't rotate them
+func importMissingSecrets(enrollment *types.Enrollment) error {
+	supplied := *enrollment
+
+	if supplied.JWTSecret != nil {
+		exists, err := secure.SigningKeyExists(secure.AccessKeyName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			logger.Info("the access token signing key exists, the supplied jwt secret is ignored")
+			supplied.JWTSecret = nil
+		}
+	}
+
+	if supplied.SessionSecret != nil {
+		secret, err := secure.GetSecret(sessionKeyName)
+		if err != nil {
+			return err
+		}
+		if secret != nil {
+			logger.Info("the session key exists, the supplied session secret is ignored")
+			supplied.SessionSecret = nil
+		}
+	}
+
+	return importSecrets(&supplied)
+}
+
+/**********************************************************************************/
+
+// enroll creates the role, organization and user of the enrollment that don't
+// exist yet, existing records are left unchanged so enrolling again is safe. The
+// user is returned when it was created
+func (auth *AuthService) enroll(enrollment *types.Enrollment) (*types.User, error) {
+	if enrollment.Role == nil || enrollment.Role.Name == "" {
+		return nil, errors.New("the enrollment requires a role")
+	}
+	if enrollment.User == nil || enrollment.User.Username == "" || enrollment.User.Password == "" {
+		return nil, errors.New("the enrollment requires a user with a username and password")
+	}
+
+	role, err := auth.findRole(enrollment.Role.Name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		if err := auth.save(enrollment.Role); err != nil {
+			return nil, err
+		}
+		logger.Infof("created role %s", enrollment.Role.Name)
+	}
+
+	// The user's organization is created if it isn't provided
+	if enrollment.Organization == nil {
+		enrollment.Organization = &types.Organization{
+			Created: time.Now(),
+			Name:    enrollment.User.Organization,
+			Active:  true,
+		}
+	}
+	if enrollment.Organization.Name == "" {
+		enrollment.Organization.Name = systemOrg
+	}
+	if enrollment.User.Organization == "" {
+		enrollment.User.Organization = enrollment.Organization.Name
+	}
+
+	org, err := auth.findOrg(enrollment.Organization.Name)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		if err := auth.save(enrollment.Organization); err != nil {
+			return nil, err
+		}
+		logger.Infof("created organization %s", enrollment.Organization.Name)
+	}
+
+	user, err := auth.getUser(enrollment.User.Username)
+	if err != nil || user != nil {
+		return nil, err
+	}
+
+	// The bootstrap administrator is trusted so it can log in when verification is
+	// enforced
+	enrollment.User.EmailVerified = true
+	if err := auth.saveUser(enrollment.User); err != nil {
+		return nil, err
+	}
+
+	return enrollment.User, nil
+}
+
+// migrateInitState records the initialization of deployments set up before the
+// state was kept in the dataplane, they have the legacy marker file or already
+// have users. It runs before the API is started so /init is never open for them
+func (auth *AuthService) migrateInitState() error {
+	state, err := auth.getInitState()
+	if err != nil || state != nil {
+		return err
+	}
+
+	initialized := util.FileExists(filepath.Join(conf.Get().WorkingFolder, initMarkerFile))
+	if !initialized {
+		users, err := dataservice.GetAll[*types.User](dataservice.Request{
+			Dataplane: conf.Get().Dataplanes[util.GetTypeName(types.User{})],
+		})
+		if err != nil {
+			return err
+		}
+		initialized = len(users) > 0
+	}
+
+	if !initialized {
+		return nil
+	}
+
+	logger.Info("recording the initialization of an existing deployment")
+	return auth.save(types.NewInitState(initMethodMigrated, ""))
+}
+
+func (auth *AuthService) getInitState() (*types.InitState, error) {
+	states, err := dataservice.Get[*types.InitState](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.InitState{})],
+		Key:        "id",
+		Value:      types.InitStateId,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil || len(states) == 0 {
+		return nil, err
+	}
+
+	return states[0], nil
+}
+
+/**********************************************************************************/
@@ -0,0 +1,89 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+/**********************************************************************************/
+
+// getSessions returns the server side sessions of the caller, admins can list the
+// sessions of any user
+func (auth *AuthService) getSessions(res http.ResponseWriter, req *http.Request) {
+	sessions, err := secure.ListSessions(auth.targetUser(req))
+	if err != nil {
+		auth.sessionError(res, err)
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, types.NewSessionInfoList(sessions, secure.SessionID(req)))
+}
+
+// deleteSession revokes a session of the caller along with its access token and
+// refresh tokens, admins can revoke the sessions of any user
+func (auth *AuthService) deleteSession(res http.ResponseWriter, req *http.Request) {
+	session, err := secure.GetSession(mux.Vars(req)["sessionid"])
+	if err != nil {
+		auth.sessionError(res, err)
+		return
+	}
+	if session == nil || session.Subject != auth.targetUser(req) {
+		auth.render.JSON(res, http.StatusNotFound, "session not found")
+		return
+	}
+
+	if err := secure.RevokeSession(session); err != nil {
+		auth.sessionError(res, err)
+		return
+	}
+
+	if session.Family != "" {
+		if err := auth.revokeRefreshFamily(session.Family); err != nil {
+			auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	auth.audit(req, types.AuditSessionRevoke, middleware.Subject(req), session.Subject)
+	auth.render.JSON(res, http.StatusOK, "the session has been revoked")
+}
+
+/**********************************************************************************/
+
+func (auth *AuthService) sessionError(res http.ResponseWriter, err error) {
+	if errors.Is(err, secure.ErrNoSessionStore) {
+		auth.render.JSON(res, http.StatusNotImplemented, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+}
+
+/**********************************************************************************/
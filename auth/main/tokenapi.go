@@ -0,0 +1,359 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+	logger "github.com/sirupsen/logrus"
+)
+
+/**********************************************************************************/
+
+func (auth *AuthService) refresh(res http.ResponseWriter, req *http.Request) {
+	cookie, err := req.Cookie(refreshCookieName)
+	if err != nil {
+		auth.render.JSON(res, http.StatusUnauthorized, "no refresh token found")
+		return
+	}
+
+	claims, err := secure.ParseJWT(secure.RefreshKeyName, cookie.Value)
+	if err != nil {
+		auth.render.JSON(res, http.StatusUnauthorized, "refresh token is not valid")
+		return
+	}
+
+	// The token is read and marked as used while holding the lock of its family
+	family, _ := claims["fam"].(string)
+	unlock := secure.LockRefreshFamily(family)
+	defer unlock()
+
+	tokenId, _ := claims["jti"].(string)
+	refreshToken, err := auth.getRefreshToken(tokenId)
+	if err != nil || refreshToken == nil {
+		auth.render.JSON(res, http.StatusUnauthorized, "refresh token is not valid")
+		return
+	}
+
+	// A refresh token that has already been exchanged is being replayed, the
+	// whole family is revoked as the token may have been stolen
+	if refreshToken.IsReused() {
+		logger.Warnf("refresh token %s for %s was reused, revoking family %s", refreshToken.TokenID, refreshToken.Subject, refreshToken.Family)
+
+		if err := auth.revokeRefreshFamily(refreshToken.Family); err != nil {
+			logger.Errorf("failed to revoke refresh token family %s: %s", refreshToken.Family, err.Error())
+		}
+
+		auth.auditFailure(req, types.AuditTokenRefresh, refreshToken.Subject, refreshToken.Family, "refresh token reused")
+		auth.expireRefreshCookie(res)
+		auth.render.JSON(res, http.StatusUnauthorized, "refresh token has already been used")
+		return
+	}
+
+	if refreshToken.IsExpired() {
+		auth.auditFailure(req, types.AuditTokenRefresh, refreshToken.Subject, refreshToken.Family, "refresh token expired")
+		auth.expireRefreshCookie(res)
+		auth.render.JSON(res, http.StatusUnauthorized, "refresh token has expired")
+		return
+	}
+
+	refreshToken.Used = time.Now()
+	if err := auth.save(refreshToken); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := auth.getUser(refreshToken.Subject)
+	if err != nil || user == nil {
+		auth.render.JSON(res, http.StatusUnauthorized, "refresh token is not valid")
+		return
+	}
+
+	auth.audit(req, types.AuditTokenRefresh, user.Id(), refreshToken.Family)
+	auth.issueTokens(res, req, user, refreshToken.Family)
+}
+
+func (auth *AuthService) logout(res http.ResponseWriter, req *http.Request) {
+	// Revoke the access token, if present, for the remainder of its lifetime
+	if authToken := middleware.TokenFromRequest(req); authToken != "" {
+		if claims, err := secure.ParseAccessToken(authToken); err == nil {
+			auth.revokeAccessToken(claims, "logout")
+		}
+	}
+
+	// Revoke the refresh token family so the refresh token can't be exchanged
+	if cookie, err := req.Cookie(refreshCookieName); err == nil {
+		if claims, err := secure.ParseJWT(secure.RefreshKeyName, cookie.Value); err == nil {
+			family, _ := claims["fam"].(string)
+			if err := auth.revokeRefreshFamily(family); err != nil {
+				logger.Errorf("failed to revoke refresh token family %s: %s", family, err.Error())
+			}
+		}
+	}
+
+	auth.expireRefreshCookie(res)
+
+	if err := secure.ClearSession(req, res); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, "successfully logged out")
+}
+
+func (auth *AuthService) revoke(res http.ResponseWriter, req *http.Request) {
+	revocation := new(types.Revocation)
+	if err := json.NewDecoder(req.Body).Decode(revocation); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch {
+	case revocation.TokenID != "":
+		// Without the token the expiration isn't known, keep the revocation for the
+		// longest lifetime an access token can have
+		accessKey, err := secure.GetSigningKey(secure.AccessKeyName)
+		if err != nil {
+			auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if err := secure.RevokeToken(revocation.TokenID, revocation.Subject, time.Now().Add(accessKey.Expiry), revocation.Reason); err != nil {
+			auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case revocation.Subject != "":
+		if err := auth.revokeSubject(revocation.Subject, revocation.Reason); err != nil {
+			auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	default:
+		auth.render.JSON(res, http.StatusBadRequest, "either 'jti' or 'sub' must be provided")
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, "successfully revoked")
+}
+
+func (auth *AuthService) getJWKS(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Cache-Control", "public, max-age=300")
+	auth.render.JSON(res, http.StatusOK, secure.PublicKeys(secure.AccessKeyName))
+}
+
+func (auth *AuthService) rotateKey(res http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+
+	algorithm := ""
+	switch name {
+	case secure.AccessKeyName:
+		algorithm = conf.Get().JWTAlgorithm
+	case secure.RefreshKeyName:
+		algorithm = secure.DefaultAlgorithm
+	default:
+		auth.render.JSON(res, http.StatusNotFound, fmt.Sprintf("signing key %s does not exist", name))
+		return
+	}
+
+	if err := secure.RotateSigningKey(name, algorithm); err != nil {
+		auth.auditFailure(req, types.AuditSecretAccess, middleware.Subject(req), name, err.Error())
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	auth.audit(req, types.AuditSecretAccess, middleware.Subject(req), name)
+
+	auth.render.JSON(res, http.StatusOK, fmt.Sprintf("successfully rotated signing key %s", name))
+}
+
+/**********************************************************************************/
+
+// issueTokens generates a new access token and refresh token for the user and
+// returns the access token
+func (auth *AuthService) issueTokens(res http.ResponseWriter, req *http.Request, user *types.User, family string) {
+	token, ok := auth.startSession(res, req, user, family)
+	if !ok {
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, token)
+}
+
+// startSession generates a new access token and refresh token for the user. The
+// access token is stored in the session, the refresh token is set as a cookie and
+// recorded so it can only be exchanged once. An error response has been rendered
+// when false is returned
+func (auth *AuthService) startSession(res http.ResponseWriter, req *http.Request, user *types.User, family string) (string, bool) {
+	if conf.Get().VerifyMode == verifyModeEnforce && !user.EmailVerified {
+		auth.render.JSON(res, http.StatusForbidden, "the email address has not been verified")
+		return "", false
+	}
+
+	accessKey, err := secure.GetSigningKey(secure.AccessKeyName)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return "", false
+	}
+
+	roles, permissions, err := auth.getPermissions(user)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return "", false
+	}
+
+	token, err := secure.GenerateJWT(accessKey, user, roles, permissions)
+	if err != nil {
+		auth.render.JSON(res, http.StatusUnauthorized, "failed to generate token")
+		return "", false
+	}
+
+	refreshKey, err := secure.GetSigningKey(secure.RefreshKeyName)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return "", false
+	}
+
+	refreshRecord := types.NewRefreshToken(user.Id(), family, refreshKey.Expiry)
+	if err := auth.save(refreshRecord); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return "", false
+	}
+
+	refreshToken, err := secure.GenerateRefreshJWT(refreshKey, user, refreshRecord)
+	if err != nil {
+		auth.render.JSON(res, http.StatusUnauthorized, "failed to generate refresh token")
+		return "", false
+	}
+
+	// Add the token to a new gorilla session, the subject and refresh token family
+	// let server side sessions be listed and revoked
+	if err := secure.StartSession(req, res, map[string]interface{}{
+		"jwt": token,
+		"sub": user.Id(),
+		"fam": refreshRecord.Family,
+	}); err != nil {
+		auth.render.JSON(res, http.StatusUnauthorized, err.Error())
+		return "", false
+	}
+
+	cookie := http.Cookie{
+		Name:     refreshCookieName,
+		Value:    refreshToken,
+		HttpOnly: true,
+		Expires:  refreshRecord.Expires,
+	}
+	http.SetCookie(res, &cookie)
+
+	return token, true
+}
+
+func (auth *AuthService) expireRefreshCookie(res http.ResponseWriter) {
+	http.SetCookie(res, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    "",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+}
+
+func (auth *AuthService) getRefreshToken(tokenId string) (*types.RefreshToken, error) {
+	return dataservice.GetItem[*types.RefreshToken](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.RefreshToken{})],
+		Key:        "id",
+		Value:      tokenId,
+		Comparator: dsapi.EQ,
+	})
+}
+
+// revokeAccessToken revokes the access token described by the claims until it
+// expires
+func (auth *AuthService) revokeAccessToken(claims jwt.MapClaims, reason string) {
+	tokenId, _ := claims["jti"].(string)
+	if tokenId == "" {
+		return
+	}
+
+	subject, _ := claims.GetSubject()
+	expires, err := claims.GetExpirationTime()
+	if err != nil || expires == nil {
+		return
+	}
+
+	if err := secure.RevokeToken(tokenId, subject, expires.Time, reason); err != nil {
+		logger.Errorf("failed to revoke token %s: %s", tokenId, err.Error())
+	}
+}
+
+// revokeSubject revokes every access token issued to the subject and every
+// refresh token the subject holds
+func (auth *AuthService) revokeSubject(subject, reason string) error {
+	if err := secure.RevokeSubject(subject, reason); err != nil {
+		return err
+	}
+
+	return auth.revokeRefreshTokens("sub", subject)
+}
+
+// revokeRefreshFamily marks every refresh token issued in the family as revoked
+func (auth *AuthService) revokeRefreshFamily(family string) error {
+	return auth.revokeRefreshTokens("family", family)
+}
+
+// revokeRefreshTokens marks every refresh token matching the key and value as
+// revoked
+func (auth *AuthService) revokeRefreshTokens(key, value string) error {
+	tokens, err := dataservice.Get[*types.RefreshToken](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.RefreshToken{})],
+		Key:        key,
+		Value:      value,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if token.Revoked {
+			continue
+		}
+
+		token.Revoked = true
+		if err := auth.save(token); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**********************************************************************************/
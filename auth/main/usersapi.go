@@ -0,0 +1,226 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sdbeard/common-services/auth/middleware"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+/**********************************************************************************/
+
+func (auth *AuthService) getUserById(res http.ResponseWriter, req *http.Request) {
+	user, ok := auth.lookupUser(res, req)
+	if !ok {
+		return
+	}
+
+	// Callers can only see the users of their own organization unless they are an
+	// admin
+	if !middleware.HasRole(req, adminRole) && middleware.Org(req) != user.Organization {
+		auth.render.JSON(res, http.StatusNotFound, "user not found")
+		return
+	}
+
+	auth.render.JSON(res, http.StatusOK, types.NewUserInfo(user))
+}
+
+// replaceUser replaces the profile, roles, organization and claims of the user,
+// the password is left unchanged
+func (auth *AuthService) replaceUser(res http.ResponseWriter, req *http.Request) {
+	user, ok := auth.lookupUser(res, req)
+	if !ok {
+		return
+	}
+
+	replacement := new(types.User)
+	if err := json.NewDecoder(req.Body).Decode(replacement); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	roles := replacement.Roles
+	if roles == nil {
+		roles = make([]string, 0)
+	}
+
+	patch := &types.UserPatch{
+		Profile:      replacement.Profile,
+		Claims:       replacement.Claims,
+		Roles:        &roles,
+		Organization: &replacement.Organization,
+	}
+
+	auth.updateUser(res, req, user, patch)
+}
+
+func (auth *AuthService) patchUser(res http.ResponseWriter, req *http.Request) {
+	user, ok := auth.lookupUser(res, req)
+	if !ok {
+		return
+	}
+
+	patch := new(types.UserPatch)
+	if err := json.NewDecoder(req.Body).Decode(patch); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	auth.updateUser(res, req, user, patch)
+}
+
+func (auth *AuthService) deleteUser(res http.ResponseWriter, req *http.Request) {
+	user, ok := auth.lookupUser(res, req)
+	if !ok {
+		return
+	}
+
+	if err := auth.remove(user); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Tokens and keys already issued to the user must stop working with the account
+	// gone
+	if err := auth.revokeSubject(user.Id(), "user deleted"); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := auth.removeAPIKeys(user.Id()); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auth.audit(req, types.AuditUserDelete, middleware.Subject(req), user.Id())
+	auth.render.JSON(res, http.StatusOK, fmt.Sprintf("successfully deleted user %s", user.Username))
+}
+
+func (auth *AuthService) setPassword(res http.ResponseWriter, req *http.Request) {
+	user, ok := auth.lookupUser(res, req)
+	if !ok {
+		return
+	}
+
+	change := new(types.PasswordChange)
+	if err := json.NewDecoder(req.Body).Decode(change); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	auth.changePassword(res, req, user, change.Password, types.AuditPasswordChange)
+}
+
+/**********************************************************************************/
+
+// lookupUser retrieves the user identified by the id path variable, rendering the
+// error response if the user can't be found
+func (auth *AuthService) lookupUser(res http.ResponseWriter, req *http.Request) (*types.User, bool) {
+	user, err := auth.getUser(mux.Vars(req)["id"])
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if user == nil {
+		auth.render.JSON(res, http.StatusNotFound, "user not found")
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (auth *AuthService) updateUser(res http.ResponseWriter, req *http.Request, user *types.User, patch *types.UserPatch) {
+	if err := types.ValidateClaims(patch.Claims); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+	if patch.Organization != nil && *patch.Organization != user.Organization {
+		if err := auth.validateOrg(*patch.Organization); err != nil {
+			auth.render.JSON(res, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	previousEmail := user.Email()
+	patch.Apply(user)
+
+	// A changed email address has to be verified again
+	emailChanged := user.Email() != previousEmail
+	if emailChanged {
+		user.EmailVerified = false
+	}
+
+	if err := auth.save(user); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if emailChanged {
+		go auth.sendVerification(user)
+	}
+
+	auth.audit(req, types.AuditUserUpdate, middleware.Subject(req), user.Id())
+	auth.render.JSON(res, http.StatusOK, types.NewUserInfo(user))
+}
+
+// changePassword re-hashes and stores the new password for the user, tokens issued
+// with the old password are revoked. The change is audited as the event
+func (auth *AuthService) changePassword(res http.ResponseWriter, req *http.Request, user *types.User, password, event string) {
+	if password == "" {
+		auth.render.JSON(res, http.StatusBadRequest, "the password is required")
+		return
+	}
+
+	hashedPassword, err := secure.GenerateHashPassword(password)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+	user.Password = hashedPassword
+
+	if err := auth.save(user); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auth.revokeSubject(user.Id(), "password changed"); err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Passwords reset with an emailed token are changed by the user themselves
+	actor := middleware.Subject(req)
+	if actor == "" {
+		actor = user.Id()
+	}
+	auth.audit(req, event, actor, user.Id())
+
+	auth.render.JSON(res, http.StatusOK, "successfully changed the password")
+}
+
+/**********************************************************************************/
@@ -0,0 +1,118 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// verifyModeEnforce refuses tokens to users that haven't verified their email, in
+// any other mode unverified users are issued tokens with email_verified=false
+const verifyModeEnforce = "enforce"
+
+/**********************************************************************************/
+
+func (auth *AuthService) verifyEmail(res http.ResponseWriter, req *http.Request) {
+	claims, err := secure.ParseJWT(secure.VerificationKeyName, req.URL.Query().Get("token"))
+	if err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, "the verification link is not valid")
+		return
+	}
+
+	subject, _ := claims.GetSubject()
+	email, _ := claims["email"].(string)
+
+	user, err := auth.getUser(subject)
+	if err != nil {
+		auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// The link only verifies the address it was sent to
+	if user == nil || user.Email() != email {
+		auth.render.JSON(res, http.StatusBadRequest, "the verification link is not valid")
+		return
+	}
+
+	if !user.EmailVerified {
+		user.EmailVerified = true
+		if err := auth.save(user); err != nil {
+			auth.render.JSON(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	auth.render.JSON(res, http.StatusOK, "the email address has been verified")
+}
+
+func (auth *AuthService) resendVerification(res http.ResponseWriter, req *http.Request) {
+	resendRequest := new(types.PasswordResetRequest)
+	if err := json.NewDecoder(req.Body).Decode(resendRequest); err != nil {
+		auth.render.JSON(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	go func() {
+		user, err := auth.getUser(resendRequest.Username)
+		if err != nil || user == nil || user.EmailVerified {
+			return
+		}
+
+		auth.sendVerification(user)
+	}()
+
+	auth.render.JSON(res, http.StatusOK, "if the account exists and is unverified a verification link has been sent")
+}
+
+/**********************************************************************************/
+
+// sendVerification emails the user a signed link that verifies their email address
+func (auth *AuthService) sendVerification(user *types.User) {
+	key, err := secure.GetSigningKey(secure.VerificationKeyName)
+	if err != nil {
+		logger.Errorf("failed to send the verification link for %s: %s", user.Id(), err.Error())
+		return
+	}
+
+	token, err := secure.GenerateVerificationJWT(key, user)
+	if err != nil {
+		logger.Errorf("failed to send the verification link for %s: %s", user.Id(), err.Error())
+		return
+	}
+
+	body := fmt.Sprintf("Please verify your email address by following the link below within %s.\n\n%s",
+		key.Expiry.String(), linkWithToken(conf.Get().VerifyURL, token))
+
+	if err := auth.sendEmail(user, "Verify your email address", body); err != nil {
+		logger.Errorf("failed to send the verification link for %s: %s", user.Id(), err.Error())
+	}
+}
+
+/**********************************************************************************/
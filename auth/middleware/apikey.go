@@ -0,0 +1,56 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package middleware
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// APIKeyHeader is the header scripts and CI present their API key in
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyResolver returns the claims of the caller an API key belongs to, nil
+// claims are returned when the key is not valid
+type APIKeyResolver func(key string) (jwt.MapClaims, error)
+
+var apiKeyResolver APIKeyResolver
+
+/***** exported functions *********************************************************/
+
+// SetAPIKeyResolver sets the function that resolves the API keys presented to the
+// Authorization middleware, API keys are refused until it is set
+func SetAPIKeyResolver(resolver APIKeyResolver) {
+	apiKeyResolver = resolver
+}
+
+/**********************************************************************************/
+
+func resolveAPIKey(key string) (jwt.MapClaims, error) {
+	if apiKeyResolver == nil {
+		return nil, nil
+	}
+
+	return apiKeyResolver(key)
+}
+
+/**********************************************************************************/
@@ -23,12 +23,11 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/sdbeard/common-services/auth/secure"
+	"github.com/sdbeard/common-services/auth/types"
 	logger "github.com/sirupsen/logrus"
 	"github.com/unrolled/render"
 )
@@ -42,61 +41,101 @@ import (
 /**********************************************************************************/
 
 func Authorization(next http.Handler) http.Handler {
+	return authorization(next, types.TokenUseAccess)
+}
+
+// UserInfoAuthorization authorizes the userinfo endpoint, it also accepts the access
+// tokens issued to OpenID Connect clients that are limited to that endpoint
+func UserInfoAuthorization(next http.Handler) http.Handler {
+	return authorization(next, types.TokenUseAccess, types.TokenUseUserInfo)
+}
+
+// TokenFromRequest returns the raw token from the session, the authorization header
+// or the auth cookie, in that order
+func TokenFromRequest(req *http.Request) string {
+	token, _ := getTokenFromSession(req)
+	return token
+}
+
+/**********************************************************************************/
+
+// authorization authenticates the caller from an API key or a token with one of the
+// accepted token uses before passing the request to the next handler
+func authorization(next http.Handler, uses ...string) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		render := render.New()
 
-		authToken := getTokenFromSession(req)
+		// API keys are resolved to the claims of the user they belong to
+		if apiKey := req.Header.Get(APIKeyHeader); apiKey != "" {
+			claims, err := resolveAPIKey(apiKey)
+			if err != nil {
+				render.JSON(res, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if claims == nil {
+				render.JSON(res, http.StatusUnauthorized, "the API key is not valid")
+				return
+			}
+
+			next.ServeHTTP(res, withPrincipal(req, claims, types.AuthMethodAPIKey))
+			return
+		}
+
+		authToken, method := getTokenFromSession(req)
 		if authToken == "" {
 			render.JSON(res, http.StatusUnauthorized, "no authorization information found")
 			return
 		}
 
-		token, err := jwt.Parse(authToken, func(token *jwt.Token) (interface{}, error) {
-			//if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("there was an error in parsing")
-			}
-			secret, _ := secure.GetSecret("jwtsecretkey")
-			return secret.Secret(), nil
-		})
+		// The token is verified with the key selected by its kid header
+		claims, err := secure.ParseAccessKeyToken(authToken, uses...)
 		if err != nil {
-			render.JSON(res, http.StatusInternalServerError, err.Error())
+			render.JSON(res, http.StatusUnauthorized, err.Error())
 			return
 		}
 
-		if !token.Valid {
-			render.JSON(res, http.StatusUnauthorized, "not authorized")
+		// Reject tokens that have been revoked before their expiration
+		revoked, err := secure.IsRevoked(claims)
+		if err != nil {
+			render.JSON(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if revoked {
+			render.JSON(res, http.StatusUnauthorized, "token has been revoked")
 			return
 		}
 
-		next.ServeHTTP(res, req)
+		// Keep the principal for the role and permission checks and the handlers
+		next.ServeHTTP(res, withPrincipal(req, claims, method))
 	})
 }
 
 /**********************************************************************************/
 
-func getTokenFromSession(req *http.Request) string {
+// getTokenFromSession returns the raw token and the authentication method matching
+// where the token was found
+func getTokenFromSession(req *http.Request) (string, string) {
 	token, err := secure.GetSessionValue[string](req, "jwt")
 	if err == nil {
-		return token
+		return token, types.AuthMethodSession
 	}
 
 	// TODO:  Need to find a away to process the error
 	return getTokenFromHeader(req)
 }
 
-func getTokenFromHeader(req *http.Request) string {
+func getTokenFromHeader(req *http.Request) (string, string) {
 	authHeader := req.Header.Get("Authorization")
 	if authHeader == "" {
-		return getTokenFromAuthCookie(req)
+		return getTokenFromAuthCookie(req), types.AuthMethodCookie
 	}
 
 	tokens := strings.Split(authHeader, "Bearer ")
 	if len(tokens) == 2 {
-		return tokens[1]
+		return tokens[1], types.AuthMethodBearer
 	}
 
-	return ""
+	return "", ""
 }
 
 func getTokenFromAuthCookie(req *http.Request) string {
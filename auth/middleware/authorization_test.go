@@ -0,0 +1,100 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+func TestAuthorizationAPIKey(t *testing.T) {
+	t.Cleanup(func() { SetAPIKeyResolver(nil) })
+
+	tests := []struct {
+		name     string
+		resolver APIKeyResolver
+		status   int
+		subject  string
+	}{
+		{
+			name:   "no resolver",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "resolved key",
+			resolver: func(key string) (jwt.MapClaims, error) {
+				return jwt.MapClaims{"sub": "kronedev", "api_key": "key-id", "roles": []string{"user"}}, nil
+			},
+			status:  http.StatusOK,
+			subject: "kronedev",
+		},
+		{
+			name: "unknown, expired or revoked key",
+			resolver: func(key string) (jwt.MapClaims, error) {
+				return nil, nil
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "resolver failure",
+			resolver: func(key string) (jwt.MapClaims, error) {
+				return nil, errors.New("the dataplane is not available")
+			},
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			SetAPIKeyResolver(test.resolver)
+
+			var caller *types.Principal
+			handler := Authorization(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				caller = PrincipalFrom(req.Context())
+				res.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(APIKeyHeader, "ak_test")
+
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			if res.Code != test.status {
+				t.Fatalf("status = %d, want %d", res.Code, test.status)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+
+			if caller == nil || caller.Subject != test.subject || caller.Method != types.AuthMethodAPIKey {
+				t.Errorf("principal = %+v, want subject %s authenticated with an API key", caller, test.subject)
+			}
+		})
+	}
+}
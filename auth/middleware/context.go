@@ -0,0 +1,77 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+/***** exported functions *********************************************************/
+
+// PrincipalFrom returns the principal the Authorization middleware stored in the
+// context, nil is returned when the request was not authenticated
+func PrincipalFrom(ctx context.Context) *types.Principal {
+	return types.PrincipalFrom(ctx)
+}
+
+// Subject returns the subject of the authenticated caller
+func Subject(req *http.Request) string {
+	return principal(req).Subject
+}
+
+// Scopes returns the OAuth2 scopes granted to the caller's token, tokens issued by
+// a direct login have no scopes
+func Scopes(req *http.Request) []string {
+	return principal(req).Scopes
+}
+
+// Org returns the organization of the authenticated caller
+func Org(req *http.Request) string {
+	return principal(req).Organization
+}
+
+// HasRole returns true if the authenticated caller holds the role
+func HasRole(req *http.Request, role string) bool {
+	return principal(req).HasRole(role)
+}
+
+/**********************************************************************************/
+
+func withPrincipal(req *http.Request, claims map[string]interface{}, method string) *http.Request {
+	return req.WithContext(types.WithPrincipal(req.Context(), types.NewPrincipal(claims, method)))
+}
+
+// principal returns the caller's principal, an empty principal is returned when
+// the request was not authenticated
+func principal(req *http.Request) *types.Principal {
+	if principal := PrincipalFrom(req.Context()); principal != nil {
+		return principal
+	}
+
+	return new(types.Principal)
+}
+
+/**********************************************************************************/
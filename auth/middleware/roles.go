@@ -0,0 +1,101 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/unrolled/render"
+)
+
+/***** exported functions *********************************************************/
+
+// RequireRoles creates a middleware that only allows callers holding at least one
+// of the passed in roles, it must follow Authorization in the chain
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			caller := principal(req)
+
+			for _, role := range roles {
+				if caller.HasRole(role) {
+					next.ServeHTTP(res, req)
+					return
+				}
+			}
+
+			render.New().JSON(res, http.StatusForbidden, fmt.Sprintf("forbidden: requires one of the roles %s", strings.Join(roles, ", ")))
+		})
+	}
+}
+
+// RequirePermission creates a middleware that only allows callers granted all of
+// the passed in permissions, it must follow Authorization in the chain
+func RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if missing := missingPermissions(principal(req), permissions); len(missing) > 0 {
+				render.New().JSON(res, http.StatusForbidden, fmt.Sprintf("forbidden: missing the permissions %s", strings.Join(missing, ", ")))
+				return
+			}
+
+			next.ServeHTTP(res, req)
+		})
+	}
+}
+
+// RequireRoleOrPermission creates a middleware that allows callers holding the
+// role or granted all of the passed in permissions, it must follow Authorization in
+// the chain
+func RequireRoleOrPermission(role string, permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			caller := principal(req)
+
+			if missing := missingPermissions(caller, permissions); len(missing) > 0 && !caller.HasRole(role) {
+				render.New().JSON(res, http.StatusForbidden, fmt.Sprintf("forbidden: requires the role %s or the permissions %s", role, strings.Join(missing, ", ")))
+				return
+			}
+
+			next.ServeHTTP(res, req)
+		})
+	}
+}
+
+/**********************************************************************************/
+
+func missingPermissions(caller *types.Principal, permissions []string) []string {
+	missing := make([]string, 0)
+	for _, permission := range permissions {
+		if !caller.HasPermission(permission) {
+			missing = append(missing, permission)
+		}
+	}
+
+	return missing
+}
+
+/**********************************************************************************/
@@ -0,0 +1,101 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+func TestRequireRoles(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		roles  []string
+		status int
+	}{
+		{name: "unauthenticated", roles: []string{"admin"}, status: http.StatusForbidden},
+		{name: "holds the role", claims: map[string]interface{}{"roles": []string{"admin"}}, roles: []string{"admin"}, status: http.StatusOK},
+		{name: "holds the role from a token", claims: map[string]interface{}{"roles": []interface{}{"user", "admin"}}, roles: []string{"admin"}, status: http.StatusOK},
+		{name: "holds one of the roles", claims: map[string]interface{}{"roles": []string{"user"}}, roles: []string{"admin", "user"}, status: http.StatusOK},
+		{name: "holds another role", claims: map[string]interface{}{"roles": []string{"user"}}, roles: []string{"admin"}, status: http.StatusForbidden},
+		{name: "holds no roles", claims: map[string]interface{}{"sub": "user"}, roles: []string{"admin"}, status: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := RequireRoles(test.roles...)(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				res.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.claims != nil {
+				req = withPrincipal(req, test.claims, types.AuthMethodBearer)
+			}
+
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			if res.Code != test.status {
+				t.Errorf("status = %d, want %d", res.Code, test.status)
+			}
+		})
+	}
+}
+
+func TestRequireRoleOrPermission(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		status int
+	}{
+		{name: "unauthenticated", status: http.StatusForbidden},
+		{name: "holds the role", claims: map[string]interface{}{"roles": []string{"admin"}}, status: http.StatusOK},
+		{name: "granted the permission", claims: map[string]interface{}{"permissions": []interface{}{"users:read"}}, status: http.StatusOK},
+		{name: "granted another permission", claims: map[string]interface{}{"permissions": []string{"users:write"}}, status: http.StatusForbidden},
+		{name: "holds another role", claims: map[string]interface{}{"roles": []string{"user"}}, status: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := RequireRoleOrPermission("admin", "users:read")(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				res.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.claims != nil {
+				req = withPrincipal(req, test.claims, types.AuthMethodBearer)
+			}
+
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			if res.Code != test.status {
+				t.Errorf("status = %d, want %d", res.Code, test.status)
+			}
+		})
+	}
+}
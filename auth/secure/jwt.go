@@ -23,49 +23,180 @@
 package secure
 
 import (
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sdbeard/common-services/auth/conf"
 	"github.com/sdbeard/common-services/auth/types"
 )
 
 /***** exported functions *********************************************************/
 
-// GenerateJWT created the
-func GenerateJWT(secret []byte, expiry time.Duration, user *types.User) (string, error) {
+// GenerateJWT creates the access token for the user signed with the signing key,
+// the roles are the user's active roles and the permissions are the effective
+// permissions resolved from them
+func GenerateJWT(key *SigningKey, user *types.User, roles, permissions []string) (string, error) {
+	return key.Sign(userClaims(key.Expiry, user, roles, permissions))
+}
+
+// APIKeyClaims creates the claims of a caller authenticated with an API key, they
+// match the claims of an access token issued to the user with the key id as jti.
+// The key is issued when it was created so revoking the user revokes the key
+func APIKeyClaims(apiKey *types.APIKey, user *types.User, roles, permissions []string) jwt.MapClaims {
+	claims := userClaims(0, user, roles, permissions)
+	claims["jti"] = apiKey.KeyID
+	claims["api_key"] = apiKey.KeyID
+	claims["iat"] = apiKey.Created.Unix()
+
+	return claims
+}
+
+// GenerateScopedJWT creates the access token for the user issued to an OpenID
+// Connect client, the token only grants access to the userinfo endpoint for the
+// scopes that were granted and carries none of the user's roles or permissions
+func GenerateScopedJWT(key *SigningKey, user *types.User, clientId string, scopes []string) (string, error) {
+	now := time.Now()
 
-	// Create the claims for the user token
 	claims := jwt.MapClaims{
-		"sub":        user.Id(),
-		"roles":      user.Roles,
-		"authorized": true,
-		"exp":        time.Now().Add(expiry).Unix(),
+		"sub":       user.Id(),
+		"jti":       uuid.NewString(),
+		"aud":       clientId,
+		"client_id": clientId,
+		"scope":     strings.Join(scopes, " "),
+		"iat":       now.Unix(),
+		"exp":       now.Add(key.Expiry).Unix(),
 	}
+	claims[types.TokenUseClaim] = types.TokenUseUserInfo
 
-	for key, value := range user.Claims {
-		claims[key] = value
+	return key.Sign(claims)
+}
+
+// GenerateIDToken creates the OpenID Connect id_token for the user issued to the
+// client, the standard claims are the claims released by the granted scopes
+func GenerateIDToken(key *SigningKey, user *types.User, clientId, issuer, nonce string, authTime time.Time, standard map[string]interface{}) (string, error) {
+	now := time.Now()
+
+	claims := jwt.MapClaims{
+		"iss":       issuer,
+		"aud":       clientId,
+		"auth_time": authTime.Unix(),
+		"iat":       now.Unix(),
+		"exp":       now.Add(key.Expiry).Unix(),
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
+	for name, value := range standard {
+		claims[name] = value
+	}
+	claims["sub"] = user.Id()
+	claims[types.TokenUseClaim] = types.TokenUseID
+
+	return key.Sign(claims)
+}
+
+// GenerateClientJWT creates the access token for an OAuth2 client signed with the
+// signing key, the granted scopes are carried as the token's permissions
+func GenerateClientJWT(key *SigningKey, client *types.Client, scopes []string) (string, error) {
+	claims := accessClaims(key.Expiry, client.Id(), client.Organization, scopes)
+	claims["client_id"] = client.Id()
+	claims["scope"] = strings.Join(scopes, " ")
+
+	return key.Sign(claims)
+}
+
+// GenerateRefreshJWT creates the signed refresh token for the user from the
+// refresh token record, the record supplies the token id, family and expiration
+func GenerateRefreshJWT(key *SigningKey, user *types.User, refresh *types.RefreshToken) (string, error) {
+	// Create the claims for the refresh token
+	claims := jwt.MapClaims{
+		"sub": user.Id(),
+		"jti": refresh.TokenID,
+		"fam": refresh.Family,
+		"iat": refresh.Created.Unix(),
+		"exp": refresh.Expires.Unix(),
+	}
+
+	return key.Sign(claims)
 }
 
-// GenerateJWT created the
-func GenerateRefreshJWT(secret []byte, user *types.User) (string, error) {
-	// Create the claims for the user token
+// GenerateVerificationJWT creates the signed token included in the link that
+// verifies the user's email address
+func GenerateVerificationJWT(key *SigningKey, user *types.User) (string, error) {
+	now := time.Now()
+
 	claims := jwt.MapClaims{
-		"sub":        user.Id(),
-		"roles":      user.Roles,
-		"authorized": true,
-		"exp":        time.Now().Add(1 * time.Hour * 24).Unix(),
+		"sub":   user.Id(),
+		"email": user.Email(),
+		"iat":   now.Unix(),
+		"exp":   now.Add(key.Expiry).Unix(),
+	}
+
+	return key.Sign(claims)
+}
+
+// GenerateMFAJWT creates the signed challenge token returned by a login that is
+// waiting on a second factor, enroll is set when the user must enroll first
+func GenerateMFAJWT(key *SigningKey, user *types.User, enroll bool) (string, error) {
+	now := time.Now()
+
+	claims := jwt.MapClaims{
+		"sub":    user.Id(),
+		"jti":    uuid.NewString(),
+		"enroll": enroll,
+		"iat":    now.Unix(),
+		"exp":    now.Add(key.Expiry).Unix(),
+	}
+
+	return key.Sign(claims)
+}
+
+/**********************************************************************************/
+
+// userClaims creates the claims of an access token issued to the user
+func userClaims(expiry time.Duration, user *types.User, roles, permissions []string) jwt.MapClaims {
+	claims := accessClaims(expiry, user.Id(), user.Organization, permissions)
+	claims["roles"] = roles
+	claims["email_verified"] = user.EmailVerified
+
+	// Custom claims never replace the standard claims
+	for name, value := range user.Claims {
+		if !types.IsReservedClaim(name) {
+			claims[name] = value
+		}
+	}
+
+	return claims
+}
+
+// accessClaims creates the claims shared by every access token, the jti identifies
+// the token so that it can be revoked before it expires. No expiration is set when
+// the expiry is zero
+func accessClaims(expiry time.Duration, subject, org string, permissions []string) jwt.MapClaims {
+	now := time.Now()
+
+	claims := jwt.MapClaims{
+		"sub":         subject,
+		"jti":         uuid.NewString(),
+		"permissions": permissions,
+		"org":         org,
+		"authorized":  true,
+		"iat":         now.Unix(),
+	}
+	claims[types.TokenUseClaim] = types.TokenUseAccess
+	if expiry > 0 {
+		claims["exp"] = now.Add(expiry).Unix()
 	}
 
-	for key, value := range user.Claims {
-		claims[key] = value
+	// The services accepting the tokens check that they are part of the audience
+	if audience := conf.Get().Audience; len(audience) > 0 {
+		claims["aud"] = audience
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
+	return claims
 }
 
 /**********************************************************************************/
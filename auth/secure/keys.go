@@ -0,0 +1,532 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package secure
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/secure/secrets"
+	"github.com/sdbeard/go-supportlib/secure/secrets/factory"
+)
+
+const (
+	// AccessKeyName is the name of the secret holding the access token signing key
+	AccessKeyName = "jwtsigningkey"
+	// RefreshKeyName is the name of the secret holding the refresh token signing key
+	RefreshKeyName = "jwtrefreshsigningkey"
+	// VerificationKeyName is the name of the secret holding the key that signs
+	// email verification links
+	VerificationKeyName = "jwtverificationkey"
+	// MFAKeyName is the name of the secret holding the key that signs the challenge
+	// tokens of logins waiting on a second factor
+	MFAKeyName = "jwtmfakey"
+	// DefaultAlgorithm is the signing algorithm used when none is configured
+	DefaultAlgorithm = "HS256"
+	// MinHMACKeySize is the minimum size in bytes of a supplied HMAC signing key,
+	// the key must be at least as long as the SHA-256 output
+	MinHMACKeySize = 32
+)
+
+var (
+	keyRings = make(map[string]*keyRing)
+	keyLock  sync.RWMutex
+	// legacyKeyNames maps the signing keys to the names the HMAC secrets were
+	// stored under before signing keys carried their algorithm
+	legacyKeyNames = map[string]string{
+		AccessKeyName:  "jwtsecretkey",
+		RefreshKeyName: "jwtrefreshsecretkey",
+	}
+)
+
+/***** SigningKey *****************************************************************/
+
+// SigningKey is a key used to sign and verify JWTs, the key id is added to the
+// header of every token it signs
+type SigningKey struct {
+	Kid       string
+	Method    jwt.SigningMethod
+	Expiry    time.Duration
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+/***** exported functions *********************************************************/
+
+// Sign creates the signed token for the claims with the key id in the header
+func (key *SigningKey) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.Kid
+
+	return token.SignedString(key.signKey)
+}
+
+// PublicKey returns the public half of an asymmetric key, HMAC keys have no public
+// key and return nil
+func (key *SigningKey) PublicKey() crypto.PublicKey {
+	if _, ok := key.Method.(*jwt.SigningMethodHMAC); ok {
+		return nil
+	}
+
+	return key.verifyKey
+}
+
+/***** exported functions *********************************************************/
+
+// LoadSigningKey loads the named signing key from the secrets manager, creating a
+// new key with the algorithm and expiry if it does not exist. An HMAC secret kept
+// under the legacy name of the key is migrated instead of creating a new key
+func LoadSigningKey(name, algorithm string, expiry time.Duration) error {
+	if algorithm == "" {
+		algorithm = DefaultAlgorithm
+	}
+
+	secret, err := retrieveJWTSecret(name)
+	if err != nil {
+		return err
+	}
+
+	if secret == nil && algorithm == "HS256" {
+		if secret, err = migrateLegacySecret(name, expiry); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case secret == nil:
+		if secret, err = newJWTSecret(name, algorithm, expiry); err != nil {
+			return err
+		}
+
+		if err = saveJWTSecret(secret, false); err != nil {
+			return err
+		}
+	case secret.Created.IsZero():
+		// Keys created before rotation was tracked start their rotation schedule now
+		secret.Created = time.Now()
+
+		if err = saveJWTSecret(secret, true); err != nil {
+			return err
+		}
+	}
+
+	return registerSecret(secret)
+}
+
+// ImportSigningKey stores the supplied HMAC key as the named signing key and
+// makes it the current key. A different existing key is rotated out so tokens it
+// signed stay valid through the grace window
+func ImportSigningKey(name, algorithm string, key []byte, expiry time.Duration) error {
+	if algorithm == "" {
+		algorithm = DefaultAlgorithm
+	}
+	if err := CheckSigningKey(algorithm, key); err != nil {
+		return fmt.Errorf("the supplied key for %s is refused: %s", name, err.Error())
+	}
+
+	secret, err := retrieveJWTSecret(name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case secret == nil:
+		secret = &types.JWTSecret{
+			Created:   time.Now(),
+			Key:       key,
+			Exp:       expiry,
+			Name:      name,
+			Algorithm: algorithm,
+		}
+	case bytes.Equal(secret.Key, key) && secret.Exp == expiry:
+		return registerSecret(secret)
+	case bytes.Equal(secret.Key, key):
+		secret.Exp = expiry
+	default:
+		secret.Rotate(key, algorithm)
+		secret.Exp = expiry
+	}
+
+	if err = saveJWTSecret(secret, true); err != nil {
+		return err
+	}
+
+	return registerSecret(secret)
+}
+
+// CheckSigningKey checks that a supplied key can sign tokens with the algorithm,
+// only HMAC keys of at least MinHMACKeySize bytes can be supplied
+func CheckSigningKey(algorithm string, key []byte) error {
+	if algorithm == "" {
+		algorithm = DefaultAlgorithm
+	}
+	if algorithm != "HS256" {
+		return fmt.Errorf("a supplied key requires the HS256 algorithm, the configured algorithm is %s", algorithm)
+	}
+	if len(key) < MinHMACKeySize {
+		return fmt.Errorf("a supplied key must be at least %d bytes", MinHMACKeySize)
+	}
+
+	return nil
+}
+
+// SigningKeyExists returns true if the named signing key, or the legacy secret it
+// is migrated from, is stored in the secrets manager
+func SigningKeyExists(name string) (bool, error) {
+	secret, err := retrieveJWTSecret(name)
+	if err != nil || secret != nil {
+		return secret != nil, err
+	}
+
+	legacyName, ok := legacyKeyNames[name]
+	if !ok {
+		return false, nil
+	}
+
+	legacy, err := get(legacyName)
+	return legacy != nil, err
+}
+
+// GetSigningKey returns the current key used to sign tokens for the named secret
+func GetSigningKey(name string) (*SigningKey, error) {
+	keyLock.RLock()
+	defer keyLock.RUnlock()
+
+	ring, ok := keyRings[name]
+	if !ok {
+		return nil, fmt.Errorf("signing key %s has not been loaded", name)
+	}
+
+	return ring.current, nil
+}
+
+// ParseJWT parses and validates a token signed by one of the keys of the named
+// secret and returns the claims contained in the token
+func ParseJWT(name, tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, Keyfunc(name))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return claims, nil
+}
+
+// ParseAccessToken parses a token signed with the access token key and checks that
+// it is an access token, id_tokens are signed with the same key
+func ParseAccessToken(tokenString string) (jwt.MapClaims, error) {
+	return ParseAccessKeyToken(tokenString, types.TokenUseAccess)
+}
+
+// ParseAccessKeyToken parses a token signed with the access token key and checks
+// that its token_use is one of the uses accepted by the caller
+func ParseAccessKeyToken(tokenString string, uses ...string) (jwt.MapClaims, error) {
+	claims, err := ParseJWT(AccessKeyName, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	use, _ := claims[types.TokenUseClaim].(string)
+	for _, accepted := range uses {
+		if use == accepted {
+			return claims, nil
+		}
+	}
+
+	return nil, fmt.Errorf("the token is not an access token")
+}
+
+// Keyfunc returns a jwt.Keyfunc that selects the verification key of the named
+// secret by the kid header of the token
+func Keyfunc(name string) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		keyLock.RLock()
+		defer keyLock.RUnlock()
+
+		ring, ok := keyRings[name]
+		if !ok {
+			return nil, fmt.Errorf("signing key %s has not been loaded", name)
+		}
+
+		key, ok := ring.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id: %s", kid)
+		}
+
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return key.verifyKey, nil
+	}
+}
+
+// PublicKeys returns the JSON Web Key Set of the public keys for the named secret,
+// HMAC keys are never published
+func PublicKeys(name string) *types.JWKSet {
+	keySet := &types.JWKSet{Keys: make([]*types.JWK, 0)}
+
+	keyLock.RLock()
+	defer keyLock.RUnlock()
+
+	ring, ok := keyRings[name]
+	if !ok {
+		return keySet
+	}
+
+	for _, key := range ring.keys {
+		publicKey := key.PublicKey()
+		if publicKey == nil {
+			continue
+		}
+
+		jwk, err := types.NewJWK(key.Kid, key.Method.Alg(), publicKey)
+		if err != nil {
+			continue
+		}
+		keySet.Keys = append(keySet.Keys, jwk)
+	}
+
+	return keySet
+}
+
+/***** keyRing ********************************************************************/
+
+// keyRing holds the current key of a named secret along with every key, by key
+// id, that tokens are verified with
+type keyRing struct {
+	secret  *types.JWTSecret
+	current *SigningKey
+	keys    map[string]*SigningKey
+}
+
+/**********************************************************************************/
+
+func registerSecret(secret *types.JWTSecret) error {
+	key, err := newSigningKey(secret.Algorithm, secret.Key, secret.Exp)
+	if err != nil {
+		return err
+	}
+
+	ring := &keyRing{
+		secret:  secret,
+		current: key,
+		keys:    map[string]*SigningKey{key.Kid: key},
+	}
+
+	// Tokens signed with the previous key are accepted until the grace window
+	// following a rotation has passed
+	if secret.InGracePeriod(gracePeriod(secret)) {
+		previous, err := newSigningKey(secret.PreviousAlgorithm, secret.Previous, secret.Exp)
+		if err != nil {
+			return err
+		}
+		ring.keys[previous.Kid] = previous
+	}
+
+	keyLock.Lock()
+	defer keyLock.Unlock()
+
+	keyRings[secret.Id()] = ring
+
+	return nil
+}
+
+// migrateLegacySecret stores the HMAC secret kept under the legacy name of the
+// signing key as the signing key, so tokens signed before the upgrade stay valid.
+// Nil is returned when there is no legacy secret
+func migrateLegacySecret(name string, expiry time.Duration) (*types.JWTSecret, error) {
+	legacyName, ok := legacyKeyNames[name]
+	if !ok {
+		return nil, nil
+	}
+
+	legacy, err := get(legacyName)
+	if err != nil || legacy == nil || len(legacy.Secret()) == 0 {
+		return nil, err
+	}
+
+	secret := &types.JWTSecret{
+		Created:   time.Now(),
+		Key:       legacy.Secret(),
+		Exp:       expiry,
+		Name:      name,
+		Algorithm: "HS256",
+	}
+	if err = saveJWTSecret(secret, false); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+func newJWTSecret(name, algorithm string, expiry time.Duration) (*types.JWTSecret, error) {
+	keyBytes, err := generateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.JWTSecret{
+		Created:   time.Now(),
+		Key:       keyBytes,
+		Exp:       expiry,
+		Name:      name,
+		Algorithm: algorithm,
+	}, nil
+}
+
+// generateKey creates new key material for the algorithm, HMAC secrets are raw
+// random bytes and private keys are PKCS #8 encoded
+func generateKey(algorithm string) ([]byte, error) {
+	var privateKey interface{}
+	var err error
+
+	switch algorithm {
+	case "HS256":
+		secret := make([]byte, 32)
+		_, err = rand.Read(secret)
+		return secret, err
+	case "RS256":
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.MarshalPKCS8PrivateKey(privateKey)
+}
+
+func newSigningKey(algorithm string, keyBytes []byte, expiry time.Duration) (*SigningKey, error) {
+	if algorithm == "" {
+		algorithm = DefaultAlgorithm
+	}
+
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	key := &SigningKey{
+		Kid:    keyId(keyBytes),
+		Method: method,
+		Expiry: expiry,
+	}
+
+	if strings.HasPrefix(algorithm, "HS") {
+		key.signKey = keyBytes
+		key.verifyKey = keyBytes
+		return key, nil
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch signKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		key.signKey, key.verifyKey = signKey, &signKey.PublicKey
+	case *ecdsa.PrivateKey:
+		key.signKey, key.verifyKey = signKey, &signKey.PublicKey
+	case ed25519.PrivateKey:
+		key.signKey, key.verifyKey = signKey, signKey.Public()
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+
+	return key, nil
+}
+
+// keyId derives a stable key id from the key material so that every instance of
+// the service computes the same id for the same key
+func keyId(keyBytes []byte) string {
+	hash := sha256.Sum256(keyBytes)
+	return base64.RawURLEncoding.EncodeToString(hash[:12])
+}
+
+func retrieveJWTSecret(name string) (*types.JWTSecret, error) {
+	manager, err := getKeyManager()
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := manager.Retrieve(
+		manager.Retrieve.WithSecretName(name),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	return found[0], nil
+}
+
+func saveJWTSecret(secret *types.JWTSecret, allowUpdate bool) error {
+	manager, err := getKeyManager()
+	if err != nil {
+		return err
+	}
+
+	return manager.Create(
+		secret,
+		manager.Create.WithContext(context.TODO()),
+		manager.Create.WithAllowUpdate(allowUpdate),
+	)
+}
+
+func getKeyManager() (*secrets.Manager[*types.JWTSecret], error) {
+	return factory.SecretsManagerFactory[*types.JWTSecret](conf.Get().SecretsConf)
+}
+
+/**********************************************************************************/
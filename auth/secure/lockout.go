@@ -0,0 +1,190 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package secure
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/common"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+)
+
+var (
+	defaultLockoutThreshold = 5
+	defaultLockoutDuration  = time.Minute
+	defaultLockoutMax       = time.Hour
+
+	// addressThresholdFactor allows a client address more failures than a single
+	// username as many users can share an address
+	addressThresholdFactor = 4
+
+	// lockoutLocks serializes the failures counted against each key so that
+	// concurrent failed logins are all counted
+	lockoutLocks = NewKeyLocks()
+)
+
+/***** exported functions *********************************************************/
+
+// ClientAddress returns the address of the client making the request, the first
+// forwarded address is used when the service is configured to trust its proxy
+func ClientAddress(req *http.Request) string {
+	if conf.Get().TrustProxy {
+		if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+// LockedOut returns how long the username or client address remains locked out,
+// zero if neither is locked
+func LockedOut(username, address string) (time.Duration, error) {
+	var remaining time.Duration
+
+	for _, key := range []string{types.UserAttemptsKey(username), types.AddressAttemptsKey(address)} {
+		attempts, err := GetLoginAttempts(key)
+		if err != nil {
+			return 0, err
+		}
+
+		if attempts != nil && attempts.IsLocked() {
+			if wait := time.Until(attempts.LockedUntil); wait > remaining {
+				remaining = wait
+			}
+		}
+	}
+
+	return remaining, nil
+}
+
+// RecordLoginFailure counts a failed login against the username and the client
+// address
+func RecordLoginFailure(username, address string) error {
+	threshold, base, max := lockoutSettings()
+
+	if err := recordFailure(types.UserAttemptsKey(username), threshold, base, max); err != nil {
+		return err
+	}
+
+	return recordFailure(types.AddressAttemptsKey(address), threshold*addressThresholdFactor, base, max)
+}
+
+// ClearLoginFailures removes the failed logins and any lockout for the key
+func ClearLoginFailures(key string) error {
+	unlock := lockoutLocks.Lock(key)
+	defer unlock()
+
+	attempts, err := GetLoginAttempts(key)
+	if err != nil || attempts == nil {
+		return err
+	}
+
+	if attempts.Failures == 0 && attempts.Lockouts == 0 {
+		return nil
+	}
+
+	return saveLoginAttempts(&types.LoginAttempts{Key: key})
+}
+
+// GetLoginAttempts returns the failed logins tracked for the key, or nil if there
+// are none
+func GetLoginAttempts(key string) (*types.LoginAttempts, error) {
+	found, err := dataservice.Get[*types.LoginAttempts](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.LoginAttempts{})],
+		Key:        "id",
+		Value:      key,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+
+	return found[0], nil
+}
+
+// GetAllLoginAttempts returns every tracked username and client address
+func GetAllLoginAttempts() ([]*types.LoginAttempts, error) {
+	return dataservice.Get[*types.LoginAttempts](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.LoginAttempts{})],
+		Key:        "type",
+		Value:      util.GetTypeName(types.LoginAttempts{}),
+		Comparator: dsapi.EQ,
+	})
+}
+
+/**********************************************************************************/
+
+func recordFailure(key string, threshold int, base, max time.Duration) error {
+	unlock := lockoutLocks.Lock(key)
+	defer unlock()
+
+	attempts, err := GetLoginAttempts(key)
+	if err != nil {
+		return err
+	}
+	if attempts == nil {
+		attempts = &types.LoginAttempts{Key: key}
+	}
+
+	attempts.RecordFailure(threshold, base, max)
+
+	return saveLoginAttempts(attempts)
+}
+
+func saveLoginAttempts(attempts *types.LoginAttempts) error {
+	return dataservice.Add[common.Document](dataservice.Request{
+		Dataplane: conf.Get().Dataplanes[util.GetTypeName(attempts)],
+		Value:     attempts,
+	})
+}
+
+func lockoutSettings() (int, time.Duration, time.Duration) {
+	threshold, base, max := conf.Get().LockoutThreshold, conf.Get().LockoutDuration, conf.Get().LockoutMax
+
+	if threshold <= 0 {
+		threshold = defaultLockoutThreshold
+	}
+	if base <= 0 {
+		base = defaultLockoutDuration
+	}
+	if max <= 0 {
+		max = defaultLockoutMax
+	}
+
+	return threshold, base, max
+}
+
+/**********************************************************************************/
@@ -0,0 +1,75 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package secure
+
+import (
+	"sync"
+)
+
+/***** KeyLocks *******************************************************************/
+
+// KeyLocks serializes work on the same key so that checking and updating a record
+// can't interleave, the lock of a key is dropped once nothing is waiting on it
+type KeyLocks struct {
+	lock sync.Mutex
+	keys map[string]*heldKey
+}
+
+type heldKey struct {
+	sync.Mutex
+	waiting int
+}
+
+// NewKeyLocks creates an empty set of key locks
+func NewKeyLocks() *KeyLocks {
+	return &KeyLocks{keys: make(map[string]*heldKey)}
+}
+
+/***** exported functions *********************************************************/
+
+// Lock locks the key and returns the function that unlocks it
+func (locks *KeyLocks) Lock(key string) func() {
+	locks.lock.Lock()
+	lock, ok := locks.keys[key]
+	if !ok {
+		lock = new(heldKey)
+		locks.keys[key] = lock
+	}
+	lock.waiting++
+	locks.lock.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		locks.lock.Lock()
+		lock.waiting--
+		if lock.waiting == 0 {
+			delete(locks.keys, key)
+		}
+		locks.lock.Unlock()
+	}
+}
+
+/**********************************************************************************/
@@ -22,7 +22,16 @@
 // *********************************************************************************
 package secure
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
 
 /***** exported functions *********************************************************/
 
@@ -39,4 +48,14 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// CheckDummyPasswordHash performs the same work as checking a real password so
+// that a login for a user that doesn't exist takes as long as one that does
+func CheckDummyPasswordHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = GenerateHashPassword("not-a-real-password")
+	})
+
+	CheckPasswordHash(password, dummyHash)
+}
+
 /**********************************************************************************/
@@ -0,0 +1,81 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package secure
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+// TestLockRefreshFamily exchanges the same refresh token concurrently, only one
+// exchange may succeed and every other one must be detected as a reuse
+func TestLockRefreshFamily(t *testing.T) {
+	tests := []struct {
+		name      string
+		exchanges int
+	}{
+		{name: "single exchange", exchanges: 1},
+		{name: "concurrent exchanges", exchanges: 50},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token := types.NewRefreshToken("user", "", time.Hour)
+
+			var exchanged, reused int32
+			var wait sync.WaitGroup
+			for i := 0; i < test.exchanges; i++ {
+				wait.Add(1)
+				go func() {
+					defer wait.Done()
+
+					unlock := LockRefreshFamily(token.Family)
+					defer unlock()
+
+					if token.IsReused() {
+						atomic.AddInt32(&reused, 1)
+						return
+					}
+					token.Used = time.Now()
+					atomic.AddInt32(&exchanged, 1)
+				}()
+			}
+			wait.Wait()
+
+			if exchanged != 1 || int(reused) != test.exchanges-1 {
+				t.Errorf("exchanged %d and reused %d, want 1 and %d", exchanged, reused, test.exchanges-1)
+			}
+
+			refreshLocks.lock.Lock()
+			_, held := refreshLocks.keys[token.Family]
+			refreshLocks.lock.Unlock()
+			if held {
+				t.Errorf("the lock of family %s was not released", token.Family)
+			}
+		})
+	}
+}
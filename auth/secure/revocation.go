@@ -0,0 +1,167 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package secure
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/common"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+)
+
+// revocationCacheTTL is how long a lookup against the revocation store is trusted
+// before the store is checked again. Revocations made by this instance are cached
+// immediately, other instances may accept a revoked token for up to this long
+var revocationCacheTTL = 15 * time.Second
+
+var (
+	revocationCache = make(map[string]*revocationEntry)
+	revocationLock  sync.RWMutex
+	// revocationCacheSize is the number of entries that triggers removing the
+	// entries that are no longer fresh
+	revocationCacheSize = 10000
+)
+
+type revocationEntry struct {
+	revocation *types.Revocation
+	checked    time.Time
+}
+
+/***** exported functions *********************************************************/
+
+// RevokeToken stores a revocation for the token with the passed in id, the token
+// is rejected until it expires
+func RevokeToken(tokenId, subject string, expires time.Time, reason string) error {
+	return storeRevocation(types.NewTokenRevocation(tokenId, subject, expires, reason))
+}
+
+// RevokeSubject stores a revocation rejecting every token issued to the subject up
+// to now
+func RevokeSubject(subject, reason string) error {
+	return storeRevocation(types.NewSubjectRevocation(subject, reason))
+}
+
+// IsRevoked checks the revocation store for the token id and the subject of the
+// passed in claims
+func IsRevoked(claims jwt.MapClaims) (bool, error) {
+	if tokenId, ok := claims["jti"].(string); ok && tokenId != "" {
+		revocation, err := getRevocation(tokenId)
+		if err != nil {
+			return false, err
+		}
+		if revocation != nil && !revocation.IsExpired() {
+			return true, nil
+		}
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return false, err
+	}
+
+	revocation, err := getRevocation(types.SubjectRevocationId(subject))
+	if err != nil || revocation == nil {
+		return false, err
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil {
+		return false, err
+	}
+
+	// Tokens without an issued at time can't be proven to be newer than the
+	// revocation
+	if issuedAt == nil {
+		return true, nil
+	}
+
+	return revocation.Covers(issuedAt.Time), nil
+}
+
+/**********************************************************************************/
+
+func storeRevocation(revocation *types.Revocation) error {
+	if err := dataservice.Add[common.Document](dataservice.Request{
+		Dataplane: conf.Get().Dataplanes[util.GetTypeName(revocation)],
+		Value:     revocation,
+	}); err != nil {
+		return err
+	}
+
+	cacheRevocation(revocation.Id(), &revocationEntry{revocation: revocation, checked: time.Now()})
+
+	return nil
+}
+
+func getRevocation(id string) (*types.Revocation, error) {
+	revocationLock.RLock()
+	entry, ok := revocationCache[id]
+	revocationLock.RUnlock()
+
+	if ok && time.Since(entry.checked) < revocationCacheTTL {
+		return entry.revocation, nil
+	}
+
+	revocations, err := dataservice.Get[*types.Revocation](dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.Revocation{})],
+		Key:        "id",
+		Value:      id,
+		Comparator: dsapi.EQ,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entry = &revocationEntry{checked: time.Now()}
+	if len(revocations) > 0 {
+		entry.revocation = revocations[0]
+	}
+
+	cacheRevocation(id, entry)
+
+	return entry.revocation, nil
+}
+
+// cacheRevocation caches the lookup of the revocation id, the entries that are no
+// longer fresh are removed once the cache is full
+func cacheRevocation(id string, entry *revocationEntry) {
+	revocationLock.Lock()
+	defer revocationLock.Unlock()
+
+	if len(revocationCache) >= revocationCacheSize {
+		for cacheKey, cached := range revocationCache {
+			if time.Since(cached.checked) >= revocationCacheTTL {
+				delete(revocationCache, cacheKey)
+			}
+		}
+	}
+	revocationCache[id] = entry
+}
+
+/**********************************************************************************/
@@ -0,0 +1,200 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package secure
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sdbeard/common-services/auth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// rotationCheckInterval is how often the rotator reloads the signing keys and
+// checks if a key is due to be rotated
+var rotationCheckInterval = time.Minute
+
+var (
+	keyGracePeriod time.Duration
+	rotationLock   sync.Mutex
+)
+
+/**********************************************************************************/
+
+// NewKeyRotator creates a rotator that rotates the named signing keys, mapped to
+// the algorithm new keys are created with, once they are older than the interval.
+// An interval of zero disables scheduled rotation but keys are still reloaded so
+// rotations made by other instances are picked up
+func NewKeyRotator(interval time.Duration, keys map[string]string) *KeyRotator {
+	return &KeyRotator{
+		interval: interval,
+		keys:     keys,
+		stop:     make(chan struct{}),
+	}
+}
+
+// SetKeyGracePeriod sets how long the previous key is accepted after a rotation,
+// when zero the expiry of the key is used so every token signed with the previous
+// key can reach its expiration
+func SetKeyGracePeriod(grace time.Duration) {
+	keyGracePeriod = grace
+}
+
+// RotateSigningKey generates a new key for the named secret, using the algorithm or
+// the current algorithm of the secret when empty, and keeps the current key as the
+// previous key
+func RotateSigningKey(name, algorithm string) error {
+	return rotateSigningKey(name, algorithm, 0)
+}
+
+// ReloadSigningKey reloads the named secret from the secrets manager
+func ReloadSigningKey(name string) error {
+	secret, err := retrieveJWTSecret(name)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return fmt.Errorf("signing key %s does not exist", name)
+	}
+
+	return registerSecret(secret)
+}
+
+/***** KeyRotator *****************************************************************/
+
+// KeyRotator periodically rotates the signing keys and moves the replaced keys
+// to the previous key of the secret
+type KeyRotator struct {
+	interval time.Duration
+	keys     map[string]string
+	stop     chan struct{}
+}
+
+/***** exported functions *********************************************************/
+
+// Start begins checking the signing keys in the background
+func (rotator *KeyRotator) Start() {
+	go func() {
+		ticker := time.NewTicker(rotationCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				rotator.check()
+			case <-rotator.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background checking of the signing keys
+func (rotator *KeyRotator) Stop() {
+	close(rotator.stop)
+}
+
+/**********************************************************************************/
+
+func (rotator *KeyRotator) check() {
+	for name, algorithm := range rotator.keys {
+		if err := ReloadSigningKey(name); err != nil {
+			logger.Errorf("failed to reload signing key %s: %s", name, err.Error())
+			continue
+		}
+
+		if rotator.interval <= 0 || !rotator.isDue(name) {
+			continue
+		}
+
+		if err := rotateSigningKey(name, algorithm, rotator.interval); err != nil {
+			logger.Errorf("failed to rotate signing key %s: %s", name, err.Error())
+		}
+	}
+}
+
+func (rotator *KeyRotator) isDue(name string) bool {
+	keyLock.RLock()
+	defer keyLock.RUnlock()
+
+	ring, ok := keyRings[name]
+	if !ok {
+		return false
+	}
+
+	return time.Since(ring.secret.Created) >= rotator.interval
+}
+
+/**********************************************************************************/
+
+// rotateSigningKey rotates the named secret once it is older than the max age, the
+// age is checked again after the secret is read so that instances sharing the
+// secret don't all rotate it. A max age of zero always rotates
+func rotateSigningKey(name, algorithm string, maxAge time.Duration) error {
+	rotationLock.Lock()
+	defer rotationLock.Unlock()
+
+	secret, err := retrieveJWTSecret(name)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return fmt.Errorf("signing key %s does not exist", name)
+	}
+
+	// Another instance rotated the key since it was loaded
+	if maxAge > 0 && time.Since(secret.Created) < maxAge {
+		return registerSecret(secret)
+	}
+
+	if algorithm == "" {
+		algorithm = secret.Algorithm
+	}
+
+	keyBytes, err := generateKey(algorithm)
+	if err != nil {
+		return err
+	}
+	secret.Rotate(keyBytes, algorithm)
+
+	if err = saveJWTSecret(secret, true); err != nil {
+		return err
+	}
+
+	logger.Infof("rotated signing key %s", name)
+
+	return registerSecret(secret)
+}
+
+/**********************************************************************************/
+
+func gracePeriod(secret *types.JWTSecret) time.Duration {
+	if keyGracePeriod > 0 {
+		return keyGracePeriod
+	}
+
+	return secret.Exp
+}
+
+/**********************************************************************************/
@@ -24,6 +24,8 @@ package secure
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/sdbeard/common-services/auth/conf"
 	"github.com/sdbeard/go-supportlib/secure/secrets"
@@ -31,6 +33,9 @@ import (
 	sectypes "github.com/sdbeard/go-supportlib/secure/types"
 )
 
+// MinSecretSize is the minimum size in bytes of a supplied secret
+const MinSecretSize = 32
+
 var (
 	secretMap   = make(map[string]*sectypes.SimpleSecret)
 	secretNames = map[string]int{"jwtsecretkey": 16, "jwtrefreshsecretkey": 16, "sessionkey": 8}
@@ -69,6 +74,36 @@ func LoadSecret(name string, size, expiry int64) error {
 	return nil
 }
 
+// ImportSecret stores the supplied value as the named secret, replacing the
+// secret if it exists
+func ImportSecret(name string, value []byte, expiry time.Duration) error {
+	if len(value) < MinSecretSize {
+		return fmt.Errorf("the supplied secret %s must be at least %d bytes", name, MinSecretSize)
+	}
+
+	manager, err := getSecretsManager()
+	if err != nil {
+		return err
+	}
+
+	secret := &sectypes.SimpleSecret{
+		Name:   name,
+		Value:  value,
+		Expiry: expiry,
+	}
+	if err = manager.Create(
+		secret,
+		manager.Create.WithContext(context.TODO()),
+		manager.Create.WithAllowUpdate(true),
+	); err != nil {
+		return err
+	}
+
+	secretMap[name] = secret
+
+	return nil
+}
+
 func GetSecret(name string) (*sectypes.SimpleSecret, error) {
 	secret, ok := secretMap[name]
 	if !ok {
@@ -25,25 +25,58 @@ package secure
 import (
 	"fmt"
 	"net/http"
+	"sync"
 
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
+	"github.com/sdbeard/common-services/auth/types"
 	"github.com/sdbeard/go-supportlib/common/util"
 )
 
 var (
-	store       *sessions.CookieStore
-	sessionName string
+	store          sessions.Store
+	storeLock      sync.RWMutex
+	sessionBackend SessionBackend
+	sessionName    string
 )
 
 /**********************************************************************************/
 
 func InitSession(secret []byte, name string) {
+	storeLock.Lock()
+	defer storeLock.Unlock()
+
 	store = sessions.NewCookieStore(secret)
 	sessionName = name
 }
 
+// InitServerSession keeps the sessions on the server in the backend so they can be
+// listed and revoked, the cookie only holds the signed session id
+func InitServerSession(secret []byte, name string, backend SessionBackend) {
+	storeLock.Lock()
+	defer storeLock.Unlock()
+
+	store = NewServerStore(backend, secret)
+	sessionBackend = backend
+	sessionName = name
+}
+
+// SetSessionSecret replaces the secret the sessions are signed with, sessions
+// signed with the previous secret are no longer accepted
+func SetSessionSecret(secret []byte) {
+	storeLock.Lock()
+	defer storeLock.Unlock()
+
+	if sessionBackend != nil {
+		store = NewServerStore(sessionBackend, secret)
+		return
+	}
+
+	store = sessions.NewCookieStore(secret)
+}
+
 func GetSessionValue[TValue any](req *http.Request, key string) (TValue, error) {
-	session, err := store.Get(req, sessionName)
+	session, err := getSession(req)
 	if err != nil {
 		return util.GetTypeObject[TValue](), err
 	}
@@ -57,10 +90,109 @@ func GetSessionValue[TValue any](req *http.Request, key string) (TValue, error)
 }
 
 func SetSessionValue(req *http.Request, res http.ResponseWriter, key string, value interface{}) error {
-	session, _ := store.Get(req, sessionName)
+	session, _ := getSession(req)
 
 	session.Values[key] = value
 	return session.Save(req, res)
 }
 
+// StartSession replaces the values of the request's session and saves it under a
+// new id, the previous server side session is deleted so that a session id known
+// before the login can't be used to take it over
+func StartSession(req *http.Request, res http.ResponseWriter, values map[string]interface{}) error {
+	session, _ := getSession(req)
+
+	if sessionBackend != nil && session.ID != "" {
+		if err := sessionBackend.Delete(session.ID); err != nil {
+			return err
+		}
+		session.ID = ""
+	}
+
+	for key := range session.Values {
+		delete(session.Values, key)
+	}
+	for key, value := range values {
+		session.Values[key] = value
+	}
+	return session.Save(req, res)
+}
+
+// SessionID returns the id of the request's server side session, cookie sessions
+// have no id
+func SessionID(req *http.Request) string {
+	session, err := getSession(req)
+	if err != nil {
+		return ""
+	}
+
+	return session.ID
+}
+
+// ListSessions returns the server side sessions of the subject
+func ListSessions(subject string) ([]*types.Session, error) {
+	if sessionBackend == nil {
+		return nil, ErrNoSessionStore
+	}
+
+	return sessionBackend.List(subject)
+}
+
+// GetSession returns the server side session with the id, nil is returned when
+// the session doesn't exist
+func GetSession(id string) (*types.Session, error) {
+	if sessionBackend == nil {
+		return nil, ErrNoSessionStore
+	}
+
+	return sessionBackend.Load(id)
+}
+
+// RevokeSession deletes the server side session and revokes the access token it
+// holds so that copies of the token stop working as well
+func RevokeSession(session *types.Session) error {
+	if sessionBackend == nil {
+		return ErrNoSessionStore
+	}
+
+	values := make(map[interface{}]interface{})
+	if err := new(securecookie.GobEncoder).Deserialize(session.Data, &values); err == nil {
+		if token, ok := values["jwt"].(string); ok {
+			if claims, err := ParseAccessToken(token); err == nil {
+				tokenId, _ := claims["jti"].(string)
+				expires, _ := claims.GetExpirationTime()
+				if tokenId != "" && expires != nil {
+					if err := RevokeToken(tokenId, session.Subject, expires.Time, "session revoked"); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return sessionBackend.Delete(session.ID)
+}
+
+// ClearSession removes all values from the session and instructs the client to
+// delete the session cookie
+func ClearSession(req *http.Request, res http.ResponseWriter) error {
+	session, _ := getSession(req)
+
+	for key := range session.Values {
+		delete(session.Values, key)
+	}
+	session.Options.MaxAge = -1
+
+	return session.Save(req, res)
+}
+
+// getSession returns the session of the request from the current store, the store
+// is replaced when the session secret changes
+func getSession(req *http.Request) (*sessions.Session, error) {
+	storeLock.RLock()
+	defer storeLock.RUnlock()
+
+	return store.Get(req, sessionName)
+}
+
 /**********************************************************************************/
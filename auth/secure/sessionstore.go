@@ -0,0 +1,366 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package secure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/sdbeard/go-supportlib/common/util"
+	"github.com/sdbeard/go-supportlib/data/types/common"
+	"github.com/sdbeard/go-supportlib/data/types/dsapi"
+	"github.com/sdbeard/go-supportlib/data/types/util/dataservice"
+	logger "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+// sessionTouchInterval limits how often the last activity of a session is saved
+var sessionTouchInterval = time.Minute
+
+// ErrNoSessionStore is returned when sessions are only kept in the client cookie
+// and can't be listed or revoked
+var ErrNoSessionStore = errors.New("sessions are not kept on the server")
+
+/***** SessionBackend *************************************************************/
+
+// SessionBackend persists the sessions of a ServerStore, Load returns nil when
+// the session doesn't exist
+type SessionBackend interface {
+	Load(id string) (*types.Session, error)
+	Save(session *types.Session) error
+	Delete(id string) error
+	List(subject string) ([]*types.Session, error)
+}
+
+// NewSessionBackend creates the session backend from the session store setting.
+// The setting is one of:
+//
+//	cookie             sessions are kept in the client cookie (the default)
+//	dataplane          sessions are kept in the Session dataplane
+//	bbolt://[File]     sessions are kept in a local bbolt file
+//
+// No backend is returned for cookie sessions
+func NewSessionBackend(setting string) (SessionBackend, error) {
+	switch {
+	case setting == "" || setting == "cookie":
+		return nil, nil
+	case setting == "dataplane":
+		return new(dataplaneSessions), nil
+	case strings.HasPrefix(setting, "bbolt://"):
+		return newBoltSessions(strings.TrimPrefix(setting, "bbolt://"))
+	}
+
+	return nil, fmt.Errorf("unknown session store: %s", setting)
+}
+
+/***** ServerStore ****************************************************************/
+
+// ServerStore is a sessions.Store that keeps the session values on the server, the
+// session cookie only holds the signed session id
+type ServerStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+	backend SessionBackend
+}
+
+// NewServerStore creates a ServerStore saving sessions to the backend, the key
+// pairs sign the session cookie as they do for a sessions.CookieStore
+func NewServerStore(backend SessionBackend, keyPairs ...[]byte) *ServerStore {
+	return &ServerStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   86400 * 30,
+			HttpOnly: true,
+		},
+		backend: backend,
+	}
+}
+
+/***** sessions.Store interface implementation ************************************/
+
+// Get returns the session registered for the request
+func (store *ServerStore) Get(req *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(req).Get(store, name)
+}
+
+// New loads the session of the session cookie, a new session is returned when
+// there is no cookie or the session no longer exists
+func (store *ServerStore) New(req *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(store, name)
+	options := *store.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := req.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var id string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &id, store.Codecs...); err != nil {
+		return session, err
+	}
+
+	record, err := store.backend.Load(id)
+	if err != nil || record == nil || record.IsExpired() {
+		return session, err
+	}
+
+	values := make(map[interface{}]interface{})
+	if err := new(securecookie.GobEncoder).Deserialize(record.Data, &values); err != nil {
+		return session, err
+	}
+
+	session.ID = id
+	session.Values = values
+	session.IsNew = false
+
+	if time.Since(record.LastActive) > sessionTouchInterval {
+		record.LastActive = time.Now()
+		record.Address = ClientAddress(req)
+		if err := store.backend.Save(record); err != nil {
+			logger.Errorf("failed to record the activity of session %s: %s", id, err.Error())
+		}
+	}
+
+	return session, nil
+}
+
+// Save stores the session values and sets the session cookie, a session with a
+// negative MaxAge is deleted
+func (store *ServerStore) Save(req *http.Request, res http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := store.backend.Delete(session.ID); err != nil {
+				return err
+			}
+		}
+
+		http.SetCookie(res, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	var record *types.Session
+	if session.ID != "" {
+		var err error
+		if record, err = store.backend.Load(session.ID); err != nil {
+			return err
+		}
+	}
+
+	if record == nil {
+		id, err := GenerateOpaqueToken(32)
+		if err != nil {
+			return err
+		}
+
+		session.ID = id
+		record = &types.Session{Created: time.Now(), ID: id}
+	}
+
+	data, err := new(securecookie.GobEncoder).Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+
+	record.Data = data
+	record.Subject, _ = session.Values["sub"].(string)
+	record.Family, _ = session.Values["fam"].(string)
+	record.Address = ClientAddress(req)
+	record.UserAgent = req.UserAgent()
+	record.LastActive = time.Now()
+	record.Expires = record.LastActive.Add(time.Duration(session.Options.MaxAge) * time.Second)
+
+	if err := store.backend.Save(record); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, store.Codecs...)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(res, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+/***** dataplaneSessions **********************************************************/
+
+// dataplaneSessions keeps the sessions in the Session dataplane so that every
+// instance of the service shares them
+type dataplaneSessions struct{}
+
+func (backend *dataplaneSessions) Load(id string) (*types.Session, error) {
+	sessions, err := dataservice.Get[*types.Session](backend.request("id", id))
+	if err != nil || len(sessions) == 0 {
+		return nil, err
+	}
+
+	return sessions[0], nil
+}
+
+func (backend *dataplaneSessions) Save(session *types.Session) error {
+	request := backend.request("", "")
+	request.Value = session
+
+	return dataservice.Add[common.Document](request)
+}
+
+func (backend *dataplaneSessions) Delete(id string) error {
+	session, err := backend.Load(id)
+	if err != nil || session == nil {
+		return err
+	}
+
+	request := backend.request("", "")
+	request.Value = session
+
+	return dataservice.Delete[common.Document](request)
+}
+
+func (backend *dataplaneSessions) List(subject string) ([]*types.Session, error) {
+	return dataservice.Get[*types.Session](backend.request("sub", subject))
+}
+
+func (backend *dataplaneSessions) request(key, value string) dataservice.Request {
+	return dataservice.Request{
+		Dataplane:  conf.Get().Dataplanes[util.GetTypeName(types.Session{})],
+		Key:        key,
+		Value:      value,
+		Comparator: dsapi.EQ,
+	}
+}
+
+/***** boltSessions ***************************************************************/
+
+var sessionBucket = []byte("sessions")
+
+// boltSessions keeps the sessions in a local bbolt file for single node
+// deployments, expired sessions are removed when sessions are listed
+type boltSessions struct {
+	db *bolt.DB
+}
+
+func newBoltSessions(file string) (*boltSessions, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(conf.Get().WorkingFolder, file)
+	}
+
+	db, err := bolt.Open(file, os.FileMode(0600), &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(sessionBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &boltSessions{db: db}, nil
+}
+
+func (backend *boltSessions) Load(id string) (*types.Session, error) {
+	var session *types.Session
+
+	err := backend.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(sessionBucket).Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+
+		session = new(types.Session)
+		return json.Unmarshal(value, session)
+	})
+
+	return session, err
+}
+
+func (backend *boltSessions) Save(session *types.Session) error {
+	value, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return backend.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionBucket).Put([]byte(session.ID), value)
+	})
+}
+
+func (backend *boltSessions) Delete(id string) error {
+	return backend.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionBucket).Delete([]byte(id))
+	})
+}
+
+func (backend *boltSessions) List(subject string) ([]*types.Session, error) {
+	sessions := make([]*types.Session, 0)
+
+	err := backend.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionBucket)
+		expired := make([][]byte, 0)
+
+		if err := bucket.ForEach(func(key, value []byte) error {
+			session := new(types.Session)
+			if err := json.Unmarshal(value, session); err != nil {
+				return err
+			}
+
+			switch {
+			case session.IsExpired():
+				expired = append(expired, key)
+			case session.Subject == subject:
+				sessions = append(sessions, session)
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return sessions, err
+}
+
+/**********************************************************************************/
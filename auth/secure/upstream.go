@@ -0,0 +1,222 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package secure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+var (
+	upstreamClient   = &http.Client{Timeout: 10 * time.Second}
+	upstreamCacheTTL = time.Hour
+	upstreamCache    = make(map[string]*upstreamMetadata)
+	upstreamLock     sync.Mutex
+)
+
+type upstreamMetadata struct {
+	config  *types.OpenIDConfiguration
+	keys    *types.JWKSet
+	fetched time.Time
+}
+
+/***** exported functions *********************************************************/
+
+// UpstreamAuthURL returns the URL of the provider's authorization endpoint that
+// starts a login with the authorization code flow and S256 PKCE
+func UpstreamAuthURL(provider *conf.IdentityProvider, redirectURI, state, nonce, verifier string) (string, error) {
+	metadata, err := getUpstreamMetadata(provider, false)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {strings.Join(provider.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.config.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return metadata.config.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// ExchangeUpstreamCode exchanges the authorization code at the provider's token
+// endpoint and returns the id_token
+func ExchangeUpstreamCode(provider *conf.IdentityProvider, redirectURI, code, verifier string) (string, error) {
+	metadata, err := getUpstreamMetadata(provider, false)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+
+	request, err := http.NewRequest(http.MethodPost, metadata.config.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(url.QueryEscape(provider.ClientID), url.QueryEscape(provider.ClientSecret))
+
+	response, err := upstreamClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("the identity provider returned status %d", response.StatusCode)
+	}
+
+	tokens := new(types.TokenResponse)
+	if err := json.NewDecoder(response.Body).Decode(tokens); err != nil {
+		return "", err
+	}
+	if tokens.IDToken == "" {
+		return "", fmt.Errorf("the identity provider did not return an id_token")
+	}
+
+	return tokens.IDToken, nil
+}
+
+// VerifyUpstreamIDToken verifies the signature, issuer, audience, expiration and
+// nonce of an id_token issued by the provider and returns its claims
+func VerifyUpstreamIDToken(provider *conf.IdentityProvider, idToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, upstreamKeyfunc(provider),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(provider.ClientID),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "EdDSA"}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if expires, err := claims.GetExpirationTime(); err != nil || expires == nil {
+		return nil, fmt.Errorf("the id_token has no expiration")
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("the id_token nonce does not match")
+	}
+
+	return claims, nil
+}
+
+/**********************************************************************************/
+
+// upstreamKeyfunc selects the provider's key by the key id of the token, the keys
+// are fetched again once when the key id is unknown in case the provider rotated
+func upstreamKeyfunc(provider *conf.IdentityProvider) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		metadata, err := getUpstreamMetadata(provider, false)
+		if err != nil {
+			return nil, err
+		}
+
+		jwk := metadata.keys.Find(kid)
+		if jwk == nil {
+			if metadata, err = getUpstreamMetadata(provider, true); err != nil {
+				return nil, err
+			}
+
+			if jwk = metadata.keys.Find(kid); jwk == nil {
+				return nil, fmt.Errorf("unknown key id %s", kid)
+			}
+		}
+
+		return jwk.PublicKey()
+	}
+}
+
+// getUpstreamMetadata returns the discovery document and keys of the provider,
+// they are cached for an hour unless a refresh is forced
+func getUpstreamMetadata(provider *conf.IdentityProvider, refresh bool) (*upstreamMetadata, error) {
+	upstreamLock.Lock()
+	defer upstreamLock.Unlock()
+
+	if metadata, ok := upstreamCache[provider.Issuer]; ok && !refresh && time.Since(metadata.fetched) < upstreamCacheTTL {
+		return metadata, nil
+	}
+
+	// The issuer may end with a slash, it is still compared exactly as configured
+	config := new(types.OpenIDConfiguration)
+	if err := fetchJSON(strings.TrimSuffix(provider.Issuer, "/")+"/.well-known/openid-configuration", config); err != nil {
+		return nil, err
+	}
+
+	if config.Issuer != provider.Issuer {
+		return nil, fmt.Errorf("the identity provider issuer %s does not match %s", config.Issuer, provider.Issuer)
+	}
+
+	keys := new(types.JWKSet)
+	if err := fetchJSON(config.JWKSURI, keys); err != nil {
+		return nil, err
+	}
+
+	metadata := &upstreamMetadata{config: config, keys: keys, fetched: time.Now()}
+	upstreamCache[provider.Issuer] = metadata
+
+	return metadata, nil
+}
+
+func fetchJSON(endpoint string, value interface{}) error {
+	response, err := upstreamClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", endpoint, response.StatusCode)
+	}
+
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+/**********************************************************************************/
@@ -0,0 +1,177 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package secure
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sdbeard/common-services/auth/conf"
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+const (
+	testKeyID    = "test-key"
+	testClientID = "test-client"
+	testNonce    = "test-nonce"
+)
+
+func TestVerifyUpstreamIDToken(t *testing.T) {
+	provider, key := newTestProvider(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"iss":   provider.Issuer,
+			"aud":   testClientID,
+			"sub":   "upstream-user",
+			"nonce": testNonce,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+		if change != nil {
+			change(claims)
+		}
+
+		return claims
+	}
+
+	tests := []struct {
+		name   string
+		key    *rsa.PrivateKey
+		kid    string
+		claims jwt.MapClaims
+		nonce  string
+		valid  bool
+	}{
+		{name: "valid", claims: claims(nil), valid: true},
+		{name: "nonce mismatch", claims: claims(nil), nonce: "other-nonce"},
+		{name: "missing nonce", claims: claims(func(c jwt.MapClaims) { delete(c, "nonce") })},
+		{name: "other audience", claims: claims(func(c jwt.MapClaims) { c["aud"] = "other-client" })},
+		{name: "missing audience", claims: claims(func(c jwt.MapClaims) { delete(c, "aud") })},
+		{name: "other issuer", claims: claims(func(c jwt.MapClaims) { c["iss"] = "https://idp.example.com" })},
+		{name: "issuer with a trailing slash", claims: claims(func(c jwt.MapClaims) { c["iss"] = provider.Issuer + "/" })},
+		{name: "expired", claims: claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })},
+		{name: "missing expiration", claims: claims(func(c jwt.MapClaims) { delete(c, "exp") })},
+		{name: "unknown key id", kid: "other-key", claims: claims(nil)},
+		{name: "signed with another key", key: otherKey, claims: claims(nil)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signingKey, kid, nonce := key, testKeyID, testNonce
+			if test.key != nil {
+				signingKey = test.key
+			}
+			if test.kid != "" {
+				kid = test.kid
+			}
+			if test.nonce != "" {
+				nonce = test.nonce
+			}
+
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, test.claims)
+			token.Header["kid"] = kid
+			idToken, err := token.SignedString(signingKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = VerifyUpstreamIDToken(provider, idToken, nonce)
+			if test.valid && err != nil {
+				t.Errorf("the id_token was refused: %s", err.Error())
+			}
+			if !test.valid && err == nil {
+				t.Error("the id_token was accepted")
+			}
+		})
+	}
+}
+
+func TestVerifyUpstreamIDTokenRefusesHMAC(t *testing.T) {
+	provider, _ := newTestProvider(t)
+
+	for _, secret := range []string{provider.ClientSecret, ""} {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"iss":   provider.Issuer,
+			"aud":   testClientID,
+			"sub":   "upstream-user",
+			"nonce": testNonce,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = testKeyID
+		idToken, err := token.SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = VerifyUpstreamIDToken(provider, idToken, testNonce); err == nil {
+			t.Error("an HMAC signed id_token was accepted")
+		}
+	}
+}
+
+// newTestProvider starts an identity provider publishing its discovery document and
+// the public key of the returned private key
+func newTestProvider(t *testing.T) (*conf.IdentityProvider, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwk, err := types.NewJWK(testKeyID, "RS256", &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := http.NewServeMux()
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	router.HandleFunc("/.well-known/openid-configuration", func(res http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(res).Encode(&types.OpenIDConfiguration{
+			Issuer:  server.URL,
+			JWKSURI: server.URL + "/jwks",
+		})
+	})
+	router.HandleFunc("/jwks", func(res http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(res).Encode(&types.JWKSet{Keys: []*types.JWK{jwk}})
+	})
+
+	return &conf.IdentityProvider{
+		Issuer:       server.URL,
+		ClientID:     testClientID,
+		ClientSecret: "test-secret",
+		Organization: "system",
+	}, key
+}
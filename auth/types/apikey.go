@@ -0,0 +1,235 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+/**********************************************************************************/
+
+// NewAPIKey creates the record of an API key for the user, only the hash of the
+// key is stored along with a short prefix to recognize it by. An expiry of zero
+// creates a key that doesn't expire
+func NewAPIKey(keyHash, prefix, name, username string, expiry time.Duration) *APIKey {
+	now := time.Now()
+
+	apiKey := &APIKey{
+		Created:  now,
+		KeyHash:  keyHash,
+		KeyID:    uuid.NewString(),
+		Prefix:   prefix,
+		Name:     name,
+		Username: username,
+	}
+	if expiry > 0 {
+		apiKey.Expires = now.Add(expiry)
+	}
+
+	return apiKey
+}
+
+/***** APIKey *********************************************************************/
+
+// APIKey is a long lived credential a user can give to scripts and CI, requests
+// made with it act as the user
+type APIKey struct {
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"lastused"`
+	Expires  time.Time `json:"expires"`
+	KeyHash  string    `json:"keyhash"`
+	KeyID    string    `json:"keyid"`
+	Prefix   string    `json:"prefix"`
+	Name     string    `json:"name"`
+	Username string    `json:"username"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the APIKey
+func (apiKey APIKey) MarshalJSON() ([]byte, error) {
+	type Alias APIKey
+
+	return json.Marshal(&struct {
+		Created  int64 `json:"created"`
+		LastUsed int64 `json:"lastused"`
+		Expires  int64 `json:"expires"`
+		Alias
+	}{
+		Created:  apiKey.Created.Unix(),
+		LastUsed: unixOrZero(apiKey.LastUsed),
+		Expires:  unixOrZero(apiKey.Expires),
+		Alias:    (Alias)(apiKey),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the APIKey
+func (apiKey *APIKey) UnmarshalJSON(data []byte) error {
+	type Alias APIKey
+	aux := &struct {
+		Created  int64 `json:"created"`
+		LastUsed int64 `json:"lastused"`
+		Expires  int64 `json:"expires"`
+		*Alias
+	}{
+		Alias: (*Alias)(apiKey),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	apiKey.Created = time.Unix(aux.Created, 0)
+	apiKey.LastUsed = timeOrZero(aux.LastUsed)
+	apiKey.Expires = timeOrZero(aux.Expires)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (apiKey *APIKey) Item() interface{} {
+	type Alias APIKey
+
+	item := &struct {
+		ID       string `json:"id"`
+		Type     string `json:"type"`
+		Created  int64  `json:"created"`
+		LastUsed int64  `json:"lastused"`
+		Expires  int64  `json:"expires"`
+		*Alias
+	}{
+		ID:       apiKey.Id(),
+		Type:     apiKey.Type(),
+		Created:  apiKey.Created.Unix(),
+		LastUsed: unixOrZero(apiKey.LastUsed),
+		Expires:  unixOrZero(apiKey.Expires),
+		Alias:    (*Alias)(apiKey),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the API key, keys are looked up by
+// their hash on every request
+func (apiKey *APIKey) Id() string {
+	return apiKey.KeyHash
+}
+
+// Type returns the reflect Type representation of the current object
+func (apiKey *APIKey) Type() string {
+	return util.GetTypeName(apiKey)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (apiKey *APIKey) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (apiKey *APIKey) Update(user string) {
+	if apiKey.Created.Unix() < 0 {
+		apiKey.Created = time.Now()
+	}
+}
+
+/***** exported functions *********************************************************/
+
+// IsExpired returns true if the key has an expiration that has passed
+func (apiKey *APIKey) IsExpired() bool {
+	return !apiKey.Expires.IsZero() && time.Now().After(apiKey.Expires)
+}
+
+/***** APIKeyRequest **************************************************************/
+
+// APIKeyRequest is the request to create an API key, the key expires after the
+// number of seconds when it is set
+type APIKeyRequest struct {
+	Name      string `json:"name"`
+	ExpiresIn int64  `json:"expiresin,omitempty"`
+}
+
+/***** APIKeyInfo *****************************************************************/
+
+// NewAPIKeyInfo creates the response representation of the API key, the key is
+// only included when it was just created
+func NewAPIKeyInfo(apiKey *APIKey, key string) *APIKeyInfo {
+	return &APIKeyInfo{
+		Created:  apiKey.Created,
+		LastUsed: apiKey.LastUsed,
+		Expires:  apiKey.Expires,
+		Key:      key,
+		KeyID:    apiKey.KeyID,
+		Prefix:   apiKey.Prefix,
+		Name:     apiKey.Name,
+		Username: apiKey.Username,
+	}
+}
+
+// NewAPIKeyInfoList creates the response representation of each of the API keys
+func NewAPIKeyInfoList(apiKeys []*APIKey) []*APIKeyInfo {
+	infoList := make([]*APIKeyInfo, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		infoList = append(infoList, NewAPIKeyInfo(apiKey, ""))
+	}
+
+	return infoList
+}
+
+// APIKeyInfo is the representation of an API key returned by the service, it
+// never includes the stored hash
+type APIKeyInfo struct {
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"lastused"`
+	Expires  time.Time `json:"expires"`
+	Key      string    `json:"key,omitempty"`
+	KeyID    string    `json:"keyid"`
+	Prefix   string    `json:"prefix"`
+	Name     string    `json:"name"`
+	Username string    `json:"username"`
+}
+
+// MarshalJSON is a method allowing serialization of the APIKeyInfo
+func (info APIKeyInfo) MarshalJSON() ([]byte, error) {
+	type Alias APIKeyInfo
+
+	return json.Marshal(&struct {
+		Created  int64 `json:"created"`
+		LastUsed int64 `json:"lastused"`
+		Expires  int64 `json:"expires"`
+		Alias
+	}{
+		Created:  info.Created.Unix(),
+		LastUsed: unixOrZero(info.LastUsed),
+		Expires:  unixOrZero(info.Expires),
+		Alias:    (Alias)(info),
+	})
+}
+
+/**********************************************************************************/
+/**********************************************************************************/
@@ -0,0 +1,203 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+// The types of the audit events
+const (
+	AuditLogin          = "login"
+	AuditTokenRefresh   = "token.refresh"
+	AuditUserCreate     = "user.create"
+	AuditUserUpdate     = "user.update"
+	AuditUserDelete     = "user.delete"
+	AuditPasswordChange = "user.password"
+	AuditPasswordReset  = "user.password.reset"
+	AuditRoleCreate     = "role.create"
+	AuditRoleUpdate     = "role.update"
+	AuditRoleDelete     = "role.delete"
+	AuditOrgCreate      = "org.create"
+	AuditOrgUpdate      = "org.update"
+	AuditOrgDelete      = "org.delete"
+	AuditOrgMember      = "org.member"
+	AuditAPIKeyCreate   = "apikey.create"
+	AuditAPIKeyRevoke   = "apikey.revoke"
+	AuditSessionRevoke  = "session.revoke"
+	AuditUnlock         = "lockout.unlock"
+	AuditSecretAccess   = "secret.access"
+	AuditInit           = "init"
+)
+
+// The outcomes of the audit events
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+/**********************************************************************************/
+
+// NewAuditEvent creates the record of an event, the reason explains the outcome
+// of failed events
+func NewAuditEvent(event, actor, target, outcome, reason string) *AuditEvent {
+	return &AuditEvent{
+		Time:    time.Now(),
+		EventID: uuid.NewString(),
+		Event:   event,
+		Actor:   actor,
+		Target:  target,
+		Outcome: outcome,
+		Reason:  reason,
+	}
+}
+
+/***** AuditEvent *****************************************************************/
+
+// AuditEvent records who did what to which target, events are only ever added to
+// the audit log
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	EventID   string    `json:"eventid"`
+	Event     string    `json:"event"`
+	Actor     string    `json:"actor,omitempty"`
+	Target    string    `json:"target,omitempty"`
+	Address   string    `json:"address,omitempty"`
+	UserAgent string    `json:"useragent,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the AuditEvent
+func (event AuditEvent) MarshalJSON() ([]byte, error) {
+	type Alias AuditEvent
+
+	return json.Marshal(&struct {
+		Time int64 `json:"time"`
+		Alias
+	}{
+		Time:  event.Time.Unix(),
+		Alias: (Alias)(event),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the
+// AuditEvent
+func (event *AuditEvent) UnmarshalJSON(data []byte) error {
+	type Alias AuditEvent
+	aux := &struct {
+		Time int64 `json:"time"`
+		*Alias
+	}{
+		Alias: (*Alias)(event),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	event.Time = time.Unix(aux.Time, 0)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (event *AuditEvent) Item() interface{} {
+	type Alias AuditEvent
+
+	item := &struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Time int64  `json:"time"`
+		*Alias
+	}{
+		ID:    event.Id(),
+		Type:  event.Type(),
+		Time:  event.Time.Unix(),
+		Alias: (*Alias)(event),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the audit event
+func (event *AuditEvent) Id() string {
+	return event.EventID
+}
+
+// Type returns the reflect Type representation of the current object
+func (event *AuditEvent) Type() string {
+	return util.GetTypeName(event)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (event *AuditEvent) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (event *AuditEvent) Update(user string) {
+	if event.Time.Unix() < 0 {
+		event.Time = time.Now()
+	}
+}
+
+/***** AuditQuery *****************************************************************/
+
+// AuditQuery selects the audit events within the time range, the actor and event
+// are only matched when they are set
+type AuditQuery struct {
+	From  time.Time
+	To    time.Time
+	Actor string
+	Event string
+	Limit int
+}
+
+/***** exported functions *********************************************************/
+
+// Matches returns true if the event is selected by the query
+func (query *AuditQuery) Matches(event *AuditEvent) bool {
+	switch {
+	case !query.From.IsZero() && event.Time.Before(query.From):
+		return false
+	case !query.To.IsZero() && event.Time.After(query.To):
+		return false
+	case query.Actor != "" && event.Actor != query.Actor:
+		return false
+	case query.Event != "" && event.Event != query.Event:
+		return false
+	}
+
+	return true
+}
+
+/**********************************************************************************/
@@ -0,0 +1,168 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+/**********************************************************************************/
+
+// NewAuthorizationCode creates the record of an OAuth2 authorization code, only the
+// hash of the code is stored
+func NewAuthorizationCode(codeHash string, request *AuthorizationRequest, subject string, authTime time.Time, expiry time.Duration) *AuthorizationCode {
+	now := time.Now()
+
+	return &AuthorizationCode{
+		Created:       now,
+		Expires:       now.Add(expiry),
+		AuthTime:      authTime,
+		CodeHash:      codeHash,
+		ClientID:      request.ClientID,
+		Subject:       subject,
+		RedirectURI:   request.RedirectURI,
+		Nonce:         request.Nonce,
+		CodeChallenge: request.CodeChallenge,
+		Scopes:        request.Scopes,
+	}
+}
+
+/***** AuthorizationCode **********************************************************/
+
+// AuthorizationCode records a short lived, single use authorization code issued to
+// a client along with the PKCE challenge it must be exchanged with
+type AuthorizationCode struct {
+	Created       time.Time `json:"created"`
+	Expires       time.Time `json:"expires"`
+	AuthTime      time.Time `json:"authtime"`
+	CodeHash      string    `json:"codehash"`
+	ClientID      string    `json:"client_id"`
+	Subject       string    `json:"sub"`
+	RedirectURI   string    `json:"redirect_uri"`
+	Nonce         string    `json:"nonce,omitempty"`
+	CodeChallenge string    `json:"code_challenge"`
+	Scopes        []string  `json:"scopes"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the AuthorizationCode
+func (code AuthorizationCode) MarshalJSON() ([]byte, error) {
+	type Alias AuthorizationCode
+
+	return json.Marshal(&struct {
+		Created  int64 `json:"created"`
+		Expires  int64 `json:"expires"`
+		AuthTime int64 `json:"authtime"`
+		Alias
+	}{
+		Created:  code.Created.Unix(),
+		Expires:  code.Expires.Unix(),
+		AuthTime: unixOrZero(code.AuthTime),
+		Alias:    (Alias)(code),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the
+// AuthorizationCode
+func (code *AuthorizationCode) UnmarshalJSON(data []byte) error {
+	type Alias AuthorizationCode
+	aux := &struct {
+		Created  int64 `json:"created"`
+		Expires  int64 `json:"expires"`
+		AuthTime int64 `json:"authtime"`
+		*Alias
+	}{
+		Alias: (*Alias)(code),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	code.Created = time.Unix(aux.Created, 0)
+	code.Expires = time.Unix(aux.Expires, 0)
+	code.AuthTime = timeOrZero(aux.AuthTime)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (code *AuthorizationCode) Item() interface{} {
+	type Alias AuthorizationCode
+
+	item := &struct {
+		ID       string `json:"id"`
+		Type     string `json:"type"`
+		Created  int64  `json:"created"`
+		Expires  int64  `json:"expires"`
+		AuthTime int64  `json:"authtime"`
+		*Alias
+	}{
+		ID:       code.Id(),
+		Type:     code.Type(),
+		Created:  code.Created.Unix(),
+		Expires:  code.Expires.Unix(),
+		AuthTime: unixOrZero(code.AuthTime),
+		Alias:    (*Alias)(code),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the authorization code
+func (code *AuthorizationCode) Id() string {
+	return code.CodeHash
+}
+
+// Type returns the reflect Type representation of the current object
+func (code *AuthorizationCode) Type() string {
+	return util.GetTypeName(code)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (code *AuthorizationCode) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (code *AuthorizationCode) Update(user string) {
+	if code.Created.Unix() < 0 {
+		code.Created = time.Now()
+	}
+}
+
+/***** exported functions *********************************************************/
+
+// IsExpired returns true if the code can no longer be exchanged
+func (code *AuthorizationCode) IsExpired() bool {
+	return time.Now().After(code.Expires)
+}
+
+/**********************************************************************************/
+/**********************************************************************************/
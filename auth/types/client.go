@@ -0,0 +1,238 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+/**********************************************************************************/
+
+// NewClient creates a client registration with a generated client id, the secret
+// hash is set by the caller
+func NewClient(registration *ClientRegistration) *Client {
+	return &Client{
+		Created:      time.Now(),
+		ClientID:     uuid.NewString(),
+		Name:         registration.Name,
+		Organization: registration.Organization,
+		Scopes:       registration.Scopes,
+		RedirectURIs: registration.RedirectURIs,
+		Public:       registration.Public,
+		Active:       true,
+	}
+}
+
+/***** Client *********************************************************************/
+
+// Client is an OAuth2 client registered to call other services with its own
+// credentials or to log users in through OpenID Connect. Only the hash of the
+// client secret is stored, public clients have no secret and must use PKCE
+type Client struct {
+	Created      time.Time `json:"created"`
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	SecretHash   string    `json:"secrethash"`
+	Organization string    `json:"org"`
+	Scopes       []string  `json:"scopes"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Public       bool      `json:"public"`
+	Active       bool      `json:"active"`
+}
+
+/***** Marshaler interface implmentation ******************************************/
+
+// MarshalJSON is a method allowing serialization of the Client
+func (client Client) MarshalJSON() ([]byte, error) {
+	type Alias Client
+
+	return json.Marshal(&struct {
+		Created int64 `json:"created"`
+		Alias
+	}{
+		Created: client.Created.Unix(),
+		Alias:   (Alias)(client),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the Client
+func (client *Client) UnmarshalJSON(data []byte) error {
+	type Alias Client
+	aux := &struct {
+		Created int64 `json:"created"`
+		*Alias
+	}{
+		Alias: (*Alias)(client),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	client.Created = time.Unix(aux.Created, 0)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (client *Client) Item() interface{} {
+	type Alias Client
+
+	item := &struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Created int64  `json:"created"`
+		*Alias
+	}{
+		ID:      client.Id(),
+		Type:    client.Type(),
+		Created: client.Created.Unix(),
+		Alias:   (*Alias)(client),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the client
+func (client *Client) Id() string {
+	return client.ClientID
+}
+
+// Type returns the reflect Type representation of the current object
+func (client *Client) Type() string {
+	return util.GetTypeName(client)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (client *Client) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (client *Client) Update(user string) {
+	if client.Created.Unix() < 0 {
+		client.Created = time.Now()
+	}
+}
+
+/***** exported functions *********************************************************/
+
+// GrantScopes returns the scopes granted for the requested scopes, every requested
+// scope must be allowed for the client. No requested scopes grants all of them
+func (client *Client) GrantScopes(requested []string) ([]string, bool) {
+	if len(requested) == 0 {
+		return client.Scopes, true
+	}
+
+	for _, scope := range requested {
+		if !slices.Contains(client.Scopes, scope) {
+			return nil, false
+		}
+	}
+
+	return requested, true
+}
+
+// AllowsRedirect returns true if the redirect URI exactly matches one of the
+// registered redirect URIs
+func (client *Client) AllowsRedirect(redirectURI string) bool {
+	return redirectURI != "" && slices.Contains(client.RedirectURIs, redirectURI)
+}
+
+/***** ClientRegistration *********************************************************/
+
+// ClientRegistration is the request to register or update a client, a client can
+// only be made public when it is registered
+type ClientRegistration struct {
+	Name         string   `json:"name"`
+	Organization string   `json:"org"`
+	Scopes       []string `json:"scopes"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Public       bool     `json:"public"`
+	Active       *bool    `json:"active,omitempty"`
+}
+
+/***** ClientInfo *****************************************************************/
+
+// NewClientInfo creates the response representation of the client, the secret is
+// only included when it was just generated
+func NewClientInfo(client *Client, secret string) *ClientInfo {
+	return &ClientInfo{
+		Created:      client.Created,
+		ClientID:     client.ClientID,
+		ClientSecret: secret,
+		Name:         client.Name,
+		Organization: client.Organization,
+		Scopes:       client.Scopes,
+		RedirectURIs: client.RedirectURIs,
+		Public:       client.Public,
+		Active:       client.Active,
+	}
+}
+
+// NewClientInfoList creates the response representation of each of the clients
+func NewClientInfoList(clients []*Client) []*ClientInfo {
+	infoList := make([]*ClientInfo, 0, len(clients))
+	for _, client := range clients {
+		infoList = append(infoList, NewClientInfo(client, ""))
+	}
+
+	return infoList
+}
+
+// ClientInfo is the representation of a client returned by the service, it never
+// includes the stored secret hash
+type ClientInfo struct {
+	Created      time.Time `json:"created"`
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	Organization string    `json:"org"`
+	Scopes       []string  `json:"scopes"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Public       bool      `json:"public"`
+	Active       bool      `json:"active"`
+}
+
+// MarshalJSON is a method allowing serialization of the ClientInfo
+func (info ClientInfo) MarshalJSON() ([]byte, error) {
+	type Alias ClientInfo
+
+	return json.Marshal(&struct {
+		Created int64 `json:"created"`
+		Alias
+	}{
+		Created: info.Created.Unix(),
+		Alias:   (Alias)(info),
+	})
+}
+
+/**********************************************************************************/
+/**********************************************************************************/
@@ -22,37 +22,69 @@
 // *********************************************************************************
 package types
 
-import "github.com/sdbeard/go-supportlib/secure/types"
+import (
+	"fmt"
+	"time"
+)
 
 /**********************************************************************************/
 
 func NewEnrollment() *Enrollment {
 	return &Enrollment{
-		Role:          &Role{},
-		User:          NewUser(),
-		JWTSecret:     &types.SimpleSecret{},
-		SessionSecret: &types.SimpleSecret{},
+		Role:         &Role{},
+		Organization: &Organization{},
+		User:         NewUser(),
 	}
 }
 
 /***** Enrollment *****************************************************************/
 
-// Enrollment represents the role, user and JWT secret is used to initialize the
-// service
+// Enrollment represents the role, organization, user and JWT secret is used to
+// initialize the service, the secrets are generated when they aren't supplied
 type Enrollment struct {
-	Role          *Role               `json:"role"`
-	User          *User               `json:"user"`
-	JWTSecret     *types.SimpleSecret `json:"jwtsecret"`
-	SessionSecret *types.SimpleSecret `json:"sessionsecret"`
+	Role          *Role             `json:"role"`
+	Organization  *Organization     `json:"org,omitempty"`
+	User          *User             `json:"user"`
+	JWTSecret     *EnrollmentSecret `json:"jwtsecret,omitempty"`
+	SessionSecret *EnrollmentSecret `json:"sessionsecret,omitempty"`
 }
 
 /***** exported functions *********************************************************/
 
 func (enroll *Enrollment) Update() {
 	enroll.Role.Update("")
+	enroll.Organization.Update("")
 	enroll.User.Update("")
-	enroll.JWTSecret.Update("")
-	enroll.SessionSecret.Update("")
+}
+
+/***** EnrollmentSecret ***********************************************************/
+
+// EnrollmentSecret is a secret supplied by the operator when initializing the
+// service, the expiry is a duration such as "1h0m0s"
+type EnrollmentSecret struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Expiry string `json:"expiry"`
+}
+
+/***** exported functions *********************************************************/
+
+// Duration parses the expiry of the secret, the fallback is used when the secret
+// has no expiry
+func (secret *EnrollmentSecret) Duration(fallback time.Duration) (time.Duration, error) {
+	if secret.Expiry == "" {
+		return fallback, nil
+	}
+
+	expiry, err := time.ParseDuration(secret.Expiry)
+	if err != nil {
+		return 0, fmt.Errorf("invalid expiry for secret %s: %s", secret.Name, err.Error())
+	}
+	if expiry <= 0 {
+		return 0, fmt.Errorf("the expiry for secret %s must be positive", secret.Name)
+	}
+
+	return expiry, nil
 }
 
 /**********************************************************************************/
@@ -0,0 +1,162 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+/**********************************************************************************/
+
+// NewFederatedIdentity creates the link between the subject of an upstream
+// identity provider and a local user
+func NewFederatedIdentity(provider, subject, username string) *FederatedIdentity {
+	now := time.Now()
+
+	return &FederatedIdentity{
+		Created:   now,
+		LastLogin: now,
+		Provider:  provider,
+		Subject:   subject,
+		Username:  username,
+	}
+}
+
+// FederatedIdentityId returns the id of the link for the provider's subject
+func FederatedIdentityId(provider, subject string) string {
+	return provider + "|" + subject
+}
+
+/***** FederatedIdentity **********************************************************/
+
+// FederatedIdentity links the subject of an upstream identity provider to the
+// local user it logs in as
+type FederatedIdentity struct {
+	Created   time.Time `json:"created"`
+	LastLogin time.Time `json:"lastlogin"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"sub"`
+	Username  string    `json:"username"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the FederatedIdentity
+func (identity FederatedIdentity) MarshalJSON() ([]byte, error) {
+	type Alias FederatedIdentity
+
+	return json.Marshal(&struct {
+		Created   int64 `json:"created"`
+		LastLogin int64 `json:"lastlogin"`
+		Alias
+	}{
+		Created:   identity.Created.Unix(),
+		LastLogin: unixOrZero(identity.LastLogin),
+		Alias:     (Alias)(identity),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the
+// FederatedIdentity
+func (identity *FederatedIdentity) UnmarshalJSON(data []byte) error {
+	type Alias FederatedIdentity
+	aux := &struct {
+		Created   int64 `json:"created"`
+		LastLogin int64 `json:"lastlogin"`
+		*Alias
+	}{
+		Alias: (*Alias)(identity),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	identity.Created = time.Unix(aux.Created, 0)
+	identity.LastLogin = timeOrZero(aux.LastLogin)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (identity *FederatedIdentity) Item() interface{} {
+	type Alias FederatedIdentity
+
+	item := &struct {
+		ID        string `json:"id"`
+		Type      string `json:"type"`
+		Created   int64  `json:"created"`
+		LastLogin int64  `json:"lastlogin"`
+		*Alias
+	}{
+		ID:        identity.Id(),
+		Type:      identity.Type(),
+		Created:   identity.Created.Unix(),
+		LastLogin: unixOrZero(identity.LastLogin),
+		Alias:     (*Alias)(identity),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the federated identity
+func (identity *FederatedIdentity) Id() string {
+	return FederatedIdentityId(identity.Provider, identity.Subject)
+}
+
+// Type returns the reflect Type representation of the current object
+func (identity *FederatedIdentity) Type() string {
+	return util.GetTypeName(identity)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (identity *FederatedIdentity) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (identity *FederatedIdentity) Update(user string) {
+	if identity.Created.Unix() < 0 {
+		identity.Created = time.Now()
+	}
+}
+
+/***** FederationState ************************************************************/
+
+// FederationState is kept in the session while the user logs in with an upstream
+// identity provider
+type FederationState struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to,omitempty"`
+}
+
+/**********************************************************************************/
+/**********************************************************************************/
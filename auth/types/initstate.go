@@ -0,0 +1,135 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+// InitStateId is the id of the single record of the initialization state
+const InitStateId = "initialized"
+
+/**********************************************************************************/
+
+// NewInitState creates the record of the service having been initialized
+func NewInitState(method, username string) *InitState {
+	return &InitState{
+		Created:  time.Now(),
+		Method:   method,
+		Username: username,
+	}
+}
+
+/***** InitState ******************************************************************/
+
+// InitState records when and how the service was initialized, the HTTP /init is
+// refused once it exists
+type InitState struct {
+	Created  time.Time `json:"created"`
+	Method   string    `json:"method"`
+	Username string    `json:"username"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the InitState
+func (state InitState) MarshalJSON() ([]byte, error) {
+	type Alias InitState
+
+	return json.Marshal(&struct {
+		Created int64 `json:"created"`
+		Alias
+	}{
+		Created: state.Created.Unix(),
+		Alias:   (Alias)(state),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the
+// InitState
+func (state *InitState) UnmarshalJSON(data []byte) error {
+	type Alias InitState
+	aux := &struct {
+		Created int64 `json:"created"`
+		*Alias
+	}{
+		Alias: (*Alias)(state),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	state.Created = time.Unix(aux.Created, 0)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (state *InitState) Item() interface{} {
+	type Alias InitState
+
+	item := &struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Created int64  `json:"created"`
+		*Alias
+	}{
+		ID:      state.Id(),
+		Type:    state.Type(),
+		Created: state.Created.Unix(),
+		Alias:   (*Alias)(state),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the initialization state
+func (state *InitState) Id() string {
+	return InitStateId
+}
+
+// Type returns the reflect Type representation of the current object
+func (state *InitState) Type() string {
+	return util.GetTypeName(state)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (state *InitState) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (state *InitState) Update(user string) {
+	if state.Created.Unix() < 0 {
+		state.Created = time.Now()
+	}
+}
+
+/**********************************************************************************/
+/**********************************************************************************/
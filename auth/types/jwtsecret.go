@@ -32,12 +32,19 @@ import (
 
 /***** JWTSecret ******************************************************************/
 
-// JWTSecret holds the values for the JWT signing string and expiration
+// JWTSecret holds the values for the JWT signing string and expiration. For HMAC
+// algorithms the key is the raw secret, for asymmetric algorithms it is the PKCS #8
+// encoded private key. When the key is rotated the replaced key is kept as the
+// previous key so tokens it signed remain valid for a grace window
 type JWTSecret struct {
-	Previous []byte        `json:"previous,omitempty"`
-	Key      []byte        `json:"key"`
-	Exp      time.Duration `json:"exp"`
-	Name     string        `json:"name"`
+	Created           time.Time     `json:"created"`
+	Rotated           time.Time     `json:"rotated"`
+	Previous          []byte        `json:"previous,omitempty"`
+	Key               []byte        `json:"key"`
+	Exp               time.Duration `json:"exp"`
+	Name              string        `json:"name"`
+	Algorithm         string        `json:"alg,omitempty"`
+	PreviousAlgorithm string        `json:"previousalg,omitempty"`
 }
 
 /***** Marshaler interfaces *******************************************************/
@@ -92,6 +99,27 @@ func (secret *JWTSecret) Expiration() time.Duration {
 	return secret.Exp
 }
 
+/***** exported functions *********************************************************/
+
+// Rotate replaces the key with the new key and algorithm, keeping the current key
+// as the previous key
+func (secret *JWTSecret) Rotate(key []byte, algorithm string) {
+	now := time.Now()
+
+	secret.Previous = secret.Key
+	secret.PreviousAlgorithm = secret.Algorithm
+	secret.Key = key
+	secret.Algorithm = algorithm
+	secret.Created = now
+	secret.Rotated = now
+}
+
+// InGracePeriod returns true if the previous key should still be accepted for
+// tokens it signed
+func (secret *JWTSecret) InGracePeriod(grace time.Duration) bool {
+	return len(secret.Previous) > 0 && time.Since(secret.Rotated) < grace
+}
+
 /***** Datasource Document interface implementation *******************************/
 
 // Item returns an object that represents the object to stored
@@ -0,0 +1,172 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+/**********************************************************************************/
+
+// UserAttemptsKey returns the key tracking the failed logins for a username
+func UserAttemptsKey(username string) string {
+	return "user:" + username
+}
+
+// AddressAttemptsKey returns the key tracking the failed logins from a client
+// address
+func AddressAttemptsKey(address string) string {
+	return "ip:" + address
+}
+
+/***** LoginAttempts **************************************************************/
+
+// LoginAttempts tracks the failed logins for a username or client address and the
+// lockout applied once too many logins have failed
+type LoginAttempts struct {
+	LastFailure time.Time `json:"lastfailure"`
+	LockedUntil time.Time `json:"lockeduntil"`
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	Lockouts    int       `json:"lockouts"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the LoginAttempts
+func (attempts LoginAttempts) MarshalJSON() ([]byte, error) {
+	type Alias LoginAttempts
+
+	return json.Marshal(&struct {
+		LastFailure int64 `json:"lastfailure"`
+		LockedUntil int64 `json:"lockeduntil"`
+		Alias
+	}{
+		LastFailure: unixOrZero(attempts.LastFailure),
+		LockedUntil: unixOrZero(attempts.LockedUntil),
+		Alias:       (Alias)(attempts),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the
+// LoginAttempts
+func (attempts *LoginAttempts) UnmarshalJSON(data []byte) error {
+	type Alias LoginAttempts
+	aux := &struct {
+		LastFailure int64 `json:"lastfailure"`
+		LockedUntil int64 `json:"lockeduntil"`
+		*Alias
+	}{
+		Alias: (*Alias)(attempts),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	attempts.LastFailure = timeOrZero(aux.LastFailure)
+	attempts.LockedUntil = timeOrZero(aux.LockedUntil)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (attempts *LoginAttempts) Item() interface{} {
+	type Alias LoginAttempts
+
+	item := &struct {
+		ID          string `json:"id"`
+		Type        string `json:"type"`
+		LastFailure int64  `json:"lastfailure"`
+		LockedUntil int64  `json:"lockeduntil"`
+		*Alias
+	}{
+		ID:          attempts.Id(),
+		Type:        attempts.Type(),
+		LastFailure: unixOrZero(attempts.LastFailure),
+		LockedUntil: unixOrZero(attempts.LockedUntil),
+		Alias:       (*Alias)(attempts),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the login attempts
+func (attempts *LoginAttempts) Id() string {
+	return attempts.Key
+}
+
+// Type returns the reflect Type representation of the current object
+func (attempts *LoginAttempts) Type() string {
+	return util.GetTypeName(attempts)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (attempts *LoginAttempts) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (attempts *LoginAttempts) Update(user string) {}
+
+/***** exported functions *********************************************************/
+
+// IsLocked returns true while a lockout is in effect
+func (attempts *LoginAttempts) IsLocked() bool {
+	return time.Now().Before(attempts.LockedUntil)
+}
+
+// RecordFailure counts a failed login, once the threshold is reached a lockout is
+// applied that doubles with every lockout up to the maximum duration
+func (attempts *LoginAttempts) RecordFailure(threshold int, base, max time.Duration) {
+	now := time.Now()
+
+	// Failures are forgotten once nothing has failed for the maximum lockout
+	if now.Sub(attempts.LastFailure) > max {
+		attempts.Failures = 0
+		attempts.Lockouts = 0
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = now
+
+	if attempts.Failures < threshold {
+		return
+	}
+
+	lockout := base << attempts.Lockouts
+	if lockout <= 0 || lockout > max {
+		lockout = max
+	}
+
+	attempts.Lockouts++
+	attempts.Failures = 0
+	attempts.LockedUntil = now.Add(lockout)
+}
+
+/**********************************************************************************/
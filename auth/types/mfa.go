@@ -0,0 +1,67 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+/***** MFASettings ****************************************************************/
+
+// MFASettings holds the TOTP multi-factor authentication state of a user. The
+// secret is pending until it is confirmed with a first code, recovery codes are
+// only stored hashed
+type MFASettings struct {
+	Secret        string   `json:"secret,omitempty"`
+	PendingSecret string   `json:"pendingsecret,omitempty"`
+	RecoveryCodes []string `json:"recoverycodes,omitempty"`
+	LastStep      int64    `json:"laststep,omitempty"`
+	Enabled       bool     `json:"enabled"`
+}
+
+/***** MFAEnrollment **************************************************************/
+
+// MFAEnrollment is returned when enrollment starts so the secret can be added to
+// an authenticator app
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+/***** MFAChallenge ***************************************************************/
+
+// MFAChallenge is returned by a login that requires a second factor, the challenge
+// token is exchanged along with a code to finish the login. Enroll is set when the
+// user must enroll before the login can finish
+type MFAChallenge struct {
+	Challenge   string `json:"challenge"`
+	MFARequired bool   `json:"mfa_required"`
+	Enroll      bool   `json:"enroll"`
+}
+
+/***** MFAVerification ************************************************************/
+
+// MFAVerification carries a TOTP or recovery code along with the challenge token
+// when one was issued
+type MFAVerification struct {
+	Challenge string `json:"challenge,omitempty"`
+	Code      string `json:"code"`
+}
+
+/**********************************************************************************/
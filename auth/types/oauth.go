@@ -0,0 +1,143 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+// The token_use claim tells the tokens signed with the access token key apart,
+// only access tokens are accepted by the API
+const (
+	TokenUseClaim    = "token_use"
+	TokenUseAccess   = "access"
+	TokenUseID       = "id"
+	TokenUseUserInfo = "userinfo"
+)
+
+/***** TokenResponse **************************************************************/
+
+// TokenResponse is the successful response of the OAuth2 token endpoint
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+	IDToken     string `json:"id_token,omitempty"`
+}
+
+/***** OAuthError *****************************************************************/
+
+// OAuthError is the error response of the OAuth2 endpoints (RFC 6749 section 5.2)
+type OAuthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+/***** Introspection ************************************************************/
+
+// NewIntrospection creates the introspection response of an active token from its
+// verified claims
+func NewIntrospection(claims map[string]interface{}) *Introspection {
+	introspection := &Introspection{
+		Active:      true,
+		TokenType:   "Bearer",
+		Roles:       stringValues(claims["roles"]),
+		Permissions: stringValues(claims["permissions"]),
+		Audience:    audienceValues(claims["aud"]),
+		Expires:     int64Value(claims["exp"]),
+		IssuedAt:    int64Value(claims["iat"]),
+	}
+
+	introspection.Subject, _ = claims["sub"].(string)
+	introspection.Username = introspection.Subject
+	introspection.TokenID, _ = claims["jti"].(string)
+	introspection.Organization, _ = claims["org"].(string)
+	introspection.Scope, _ = claims["scope"].(string)
+	introspection.ClientID, _ = claims["client_id"].(string)
+	introspection.Issuer, _ = claims["iss"].(string)
+
+	return introspection
+}
+
+// Introspection is the response of the token introspection endpoint (RFC 7662),
+// only active is set for a token that is not active
+type Introspection struct {
+	Active       bool     `json:"active"`
+	Scope        string   `json:"scope,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	Username     string   `json:"username,omitempty"`
+	TokenType    string   `json:"token_type,omitempty"`
+	Expires      int64    `json:"exp,omitempty"`
+	IssuedAt     int64    `json:"iat,omitempty"`
+	Subject      string   `json:"sub,omitempty"`
+	Issuer       string   `json:"iss,omitempty"`
+	TokenID      string   `json:"jti,omitempty"`
+	Organization string   `json:"org,omitempty"`
+	Roles        []string `json:"roles,omitempty"`
+	Permissions  []string `json:"permissions,omitempty"`
+	Audience     []string `json:"aud,omitempty"`
+}
+
+/**********************************************************************************/
+
+// stringValues returns the strings of a list claim, claims parsed from a token are
+// []interface{} while claims built by the service are []string
+func stringValues(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+// audienceValues returns the audience claim, which is either a single string or a
+// list of strings
+func audienceValues(value interface{}) []string {
+	if audience, ok := value.(string); ok {
+		return []string{audience}
+	}
+
+	return stringValues(value)
+}
+
+// int64Value returns a numeric claim, claims parsed from a token are float64
+func int64Value(value interface{}) int64 {
+	switch number := value.(type) {
+	case float64:
+		return int64(number)
+	case int64:
+		return number
+	case int:
+		return int64(number)
+	}
+
+	return 0
+}
+
+/**********************************************************************************/
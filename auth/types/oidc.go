@@ -0,0 +1,127 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"slices"
+	"strings"
+)
+
+// StandardScopes are the OpenID Connect scopes every client may request, they
+// select the standard claims released about the user
+var StandardScopes = []string{"openid", "profile", "email", "phone", "address"}
+
+/***** AuthorizationRequest *******************************************************/
+
+// AuthorizationRequest holds the parameters of an OpenID Connect authorization
+// request (OpenID Connect Core section 3.1.2.1)
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	State               string
+	Nonce               string
+	Prompt              string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Scopes              []string
+}
+
+/***** OpenIDConfiguration ********************************************************/
+
+// OpenIDConfiguration is the OpenID Connect discovery document
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint,omitempty"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+/***** exported functions *********************************************************/
+
+// NewStandardClaims returns the OpenID Connect standard claims of the user that
+// are released by the scopes
+func NewStandardClaims(user *User, scopes []string) map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub": user.Id(),
+	}
+
+	profile := user.Profile
+	if profile == nil {
+		profile = new(UserProfile)
+	}
+
+	if slices.Contains(scopes, "profile") {
+		claims["preferred_username"] = user.Username
+		claims["updated_at"] = user.Created.Unix()
+		setClaim(claims, "given_name", profile.FirstName)
+		setClaim(claims, "family_name", profile.LastName)
+		setClaim(claims, "name", strings.TrimSpace(profile.FirstName+" "+profile.LastName))
+	}
+
+	if slices.Contains(scopes, "email") {
+		claims["email"] = user.Email()
+		claims["email_verified"] = user.EmailVerified
+	}
+
+	if slices.Contains(scopes, "phone") {
+		setClaim(claims, "phone_number", profile.Phone)
+	}
+
+	if slices.Contains(scopes, "address") && profile.Address.Address1 != "" {
+		street := profile.Address.Address1
+		if profile.Address.Address2 != "" {
+			street += "\n" + profile.Address.Address2
+		}
+
+		claims["address"] = map[string]string{
+			"street_address": street,
+			"locality":       profile.Address.City,
+			"region":         profile.Address.State,
+			"postal_code":    profile.Address.PostalCode,
+			"country":        profile.Address.Country,
+		}
+	}
+
+	return claims
+}
+
+/**********************************************************************************/
+
+func setClaim(claims map[string]interface{}, name, value string) {
+	if value != "" {
+		claims[name] = value
+	}
+}
+
+/**********************************************************************************/
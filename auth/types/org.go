@@ -22,5 +22,102 @@
 // *********************************************************************************
 package types
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+/**********************************************************************************/
+/***** Organization ***************************************************************/
+
+// Organization is a tenant of the service, every user belongs to exactly one
+// organization
+type Organization struct {
+	Created     time.Time `json:"created"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+}
+
+/***** Marshaler interface implmentation ******************************************/
+
+// MarshalJSON is a method allowing serialization of the Organization
+func (org Organization) MarshalJSON() ([]byte, error) {
+	type Alias Organization
+
+	return json.Marshal(&struct {
+		Created int64 `json:"created"`
+		Alias
+	}{
+		Created: org.Created.Unix(),
+		Alias:   (Alias)(org),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the
+// Organization
+func (org *Organization) UnmarshalJSON(data []byte) error {
+	type Alias Organization
+	aux := &struct {
+		Created int64 `json:"created"`
+		*Alias
+	}{
+		Alias: (*Alias)(org),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	org.Created = time.Unix(aux.Created, 0)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (org *Organization) Item() interface{} {
+	type Alias Organization
+
+	item := &struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Created int64  `json:"created"`
+		*Alias
+	}{
+		ID:      org.Id(),
+		Type:    org.Type(),
+		Created: org.Created.Unix(),
+		Alias:   (*Alias)(org),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the organization
+func (org *Organization) Id() string {
+	return org.Name
+}
+
+// Type returns the reflect Type representation of the current object
+func (org *Organization) Type() string {
+	return util.GetTypeName(org)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (org *Organization) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (org *Organization) Update(user string) {
+	if org.Created.Unix() < 0 {
+		org.Created = time.Now()
+	}
+}
+
 /**********************************************************************************/
 /**********************************************************************************/
@@ -0,0 +1,123 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The methods a principal authenticated with
+const (
+	AuthMethodSession = "session"
+	AuthMethodBearer  = "bearer"
+	AuthMethodCookie  = "cookie"
+	AuthMethodAPIKey  = "apikey"
+)
+
+type principalKey struct{}
+
+/**********************************************************************************/
+
+// NewPrincipal creates the principal from the verified claims of the caller's
+// access token, the method records how the token was presented
+func NewPrincipal(claims map[string]interface{}, method string) *Principal {
+	principal := &Principal{
+		Claims:      claims,
+		Roles:       stringValues(claims["roles"]),
+		Permissions: stringValues(claims["permissions"]),
+		Audience:    audienceValues(claims["aud"]),
+		Method:      method,
+	}
+
+	principal.Subject, _ = claims["sub"].(string)
+	principal.Organization, _ = claims["org"].(string)
+	principal.TokenID, _ = claims["jti"].(string)
+	principal.ClientID, _ = claims["client_id"].(string)
+
+	if scope, ok := claims["scope"].(string); ok {
+		principal.Scopes = strings.Fields(scope)
+	}
+	if expires := int64Value(claims["exp"]); expires > 0 {
+		principal.Expires = time.Unix(expires, 0)
+	}
+
+	return principal
+}
+
+// WithPrincipal returns a copy of the context holding the principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal held by the context, nil is returned when
+// the context has no principal
+func PrincipalFrom(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+/***** Principal ******************************************************************/
+
+// Principal is the authenticated caller of a request. Client credentials tokens
+// have the client id as the subject and API keys have the key id as the token id
+type Principal struct {
+	Expires      time.Time              `json:"expires"`
+	Claims       map[string]interface{} `json:"-"`
+	Roles        []string               `json:"roles"`
+	Permissions  []string               `json:"permissions"`
+	Scopes       []string               `json:"scopes,omitempty"`
+	Audience     []string               `json:"aud,omitempty"`
+	Subject      string                 `json:"sub"`
+	Organization string                 `json:"org"`
+	TokenID      string                 `json:"jti"`
+	ClientID     string                 `json:"client_id,omitempty"`
+	Method       string                 `json:"method"`
+}
+
+/***** exported functions *********************************************************/
+
+// HasRole returns true if the principal holds the role
+func (principal *Principal) HasRole(role string) bool {
+	return slices.Contains(principal.Roles, role)
+}
+
+// HasPermission returns true if the principal was granted the permission
+func (principal *Principal) HasPermission(permission string) bool {
+	return slices.Contains(principal.Permissions, permission)
+}
+
+// HasScope returns true if the principal's token was granted the OAuth2 scope
+func (principal *Principal) HasScope(scope string) bool {
+	return slices.Contains(principal.Scopes, scope)
+}
+
+// IsClient returns true if the principal is an OAuth2 client acting on its own
+// behalf rather than a user
+func (principal *Principal) IsClient() bool {
+	return principal.ClientID != "" && principal.ClientID == principal.Subject
+}
+
+/**********************************************************************************/
@@ -0,0 +1,177 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+/**********************************************************************************/
+
+// NewRefreshToken creates a new refresh token record for the subject. If the
+// family is empty the token starts a new family rooted at itself
+func NewRefreshToken(subject, family string, expiry time.Duration) *RefreshToken {
+	now := time.Now()
+	tokenId := uuid.NewString()
+
+	if family == "" {
+		family = tokenId
+	}
+
+	return &RefreshToken{
+		Created: now,
+		Expires: now.Add(expiry),
+		TokenID: tokenId,
+		Family:  family,
+		Subject: subject,
+	}
+}
+
+/***** RefreshToken ***************************************************************/
+
+// RefreshToken records an issued refresh token. Each refresh token may only be
+// exchanged once, every token issued from the same login shares a family so a
+// replayed token can revoke all of them
+type RefreshToken struct {
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+	Used    time.Time `json:"used"`
+	TokenID string    `json:"jti"`
+	Family  string    `json:"family"`
+	Subject string    `json:"sub"`
+	Revoked bool      `json:"revoked"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the RefreshToken
+func (token RefreshToken) MarshalJSON() ([]byte, error) {
+	type Alias RefreshToken
+
+	return json.Marshal(&struct {
+		Created int64 `json:"created"`
+		Expires int64 `json:"expires"`
+		Used    int64 `json:"used"`
+		Alias
+	}{
+		Created: token.Created.Unix(),
+		Expires: token.Expires.Unix(),
+		Used:    unixOrZero(token.Used),
+		Alias:   (Alias)(token),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the
+// RefreshToken
+func (token *RefreshToken) UnmarshalJSON(data []byte) error {
+	type Alias RefreshToken
+	aux := &struct {
+		Created int64 `json:"created"`
+		Expires int64 `json:"expires"`
+		Used    int64 `json:"used"`
+		*Alias
+	}{
+		Alias: (*Alias)(token),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	token.Created = time.Unix(aux.Created, 0)
+	token.Expires = time.Unix(aux.Expires, 0)
+	token.Used = timeOrZero(aux.Used)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (token *RefreshToken) Item() interface{} {
+	type Alias RefreshToken
+
+	item := &struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Created int64  `json:"created"`
+		Expires int64  `json:"expires"`
+		Used    int64  `json:"used"`
+		*Alias
+	}{
+		ID:      token.Id(),
+		Type:    token.Type(),
+		Created: token.Created.Unix(),
+		Expires: token.Expires.Unix(),
+		Used:    unixOrZero(token.Used),
+		Alias:   (*Alias)(token),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the refresh token
+func (token *RefreshToken) Id() string {
+	return token.TokenID
+}
+
+// Type returns the reflect Type representation of the current object
+func (token *RefreshToken) Type() string {
+	return util.GetTypeName(token)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (token *RefreshToken) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (token *RefreshToken) Update(user string) {
+	if token.Created.Unix() < 0 {
+		token.Created = time.Now()
+	}
+}
+
+/***** exported functions *********************************************************/
+
+// IsUsed returns true if the refresh token has already been exchanged
+func (token *RefreshToken) IsUsed() bool {
+	return !token.Used.IsZero()
+}
+
+// IsReused returns true if the refresh token can no longer be exchanged because it
+// was exchanged or revoked before, presenting it again means it may be stolen
+func (token *RefreshToken) IsReused() bool {
+	return token.IsUsed() || token.Revoked
+}
+
+// IsExpired returns true if the refresh token is past its expiration
+func (token *RefreshToken) IsExpired() bool {
+	return time.Now().After(token.Expires)
+}
+
+/**********************************************************************************/
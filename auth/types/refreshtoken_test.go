@@ -0,0 +1,49 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRefreshTokenIsReused(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  *RefreshToken
+		reused bool
+	}{
+		{name: "unused", token: &RefreshToken{}, reused: false},
+		{name: "exchanged", token: &RefreshToken{Used: time.Now()}, reused: true},
+		{name: "revoked", token: &RefreshToken{Revoked: true}, reused: true},
+		{name: "exchanged and revoked", token: &RefreshToken{Used: time.Now(), Revoked: true}, reused: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if reused := test.token.IsReused(); reused != test.reused {
+				t.Errorf("IsReused() = %t, want %t", reused, test.reused)
+			}
+		})
+	}
+}
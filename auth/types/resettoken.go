@@ -0,0 +1,170 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+/**********************************************************************************/
+
+// NewResetToken creates the record of a password reset token, only the hash of
+// the token is stored
+func NewResetToken(tokenHash, username string, expiry time.Duration) *ResetToken {
+	now := time.Now()
+
+	return &ResetToken{
+		Created:   now,
+		Expires:   now.Add(expiry),
+		TokenHash: tokenHash,
+		Username:  username,
+	}
+}
+
+/***** ResetToken *****************************************************************/
+
+// ResetToken records a short lived, single use password reset token
+type ResetToken struct {
+	Created   time.Time `json:"created"`
+	Expires   time.Time `json:"expires"`
+	Used      time.Time `json:"used"`
+	TokenHash string    `json:"tokenhash"`
+	Username  string    `json:"username"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the ResetToken
+func (token ResetToken) MarshalJSON() ([]byte, error) {
+	type Alias ResetToken
+
+	return json.Marshal(&struct {
+		Created int64 `json:"created"`
+		Expires int64 `json:"expires"`
+		Used    int64 `json:"used"`
+		Alias
+	}{
+		Created: token.Created.Unix(),
+		Expires: token.Expires.Unix(),
+		Used:    unixOrZero(token.Used),
+		Alias:   (Alias)(token),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the
+// ResetToken
+func (token *ResetToken) UnmarshalJSON(data []byte) error {
+	type Alias ResetToken
+	aux := &struct {
+		Created int64 `json:"created"`
+		Expires int64 `json:"expires"`
+		Used    int64 `json:"used"`
+		*Alias
+	}{
+		Alias: (*Alias)(token),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	token.Created = time.Unix(aux.Created, 0)
+	token.Expires = time.Unix(aux.Expires, 0)
+	token.Used = timeOrZero(aux.Used)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (token *ResetToken) Item() interface{} {
+	type Alias ResetToken
+
+	item := &struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Created int64  `json:"created"`
+		Expires int64  `json:"expires"`
+		Used    int64  `json:"used"`
+		*Alias
+	}{
+		ID:      token.Id(),
+		Type:    token.Type(),
+		Created: token.Created.Unix(),
+		Expires: token.Expires.Unix(),
+		Used:    unixOrZero(token.Used),
+		Alias:   (*Alias)(token),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the reset token
+func (token *ResetToken) Id() string {
+	return token.TokenHash
+}
+
+// Type returns the reflect Type representation of the current object
+func (token *ResetToken) Type() string {
+	return util.GetTypeName(token)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (token *ResetToken) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (token *ResetToken) Update(user string) {
+	if token.Created.Unix() < 0 {
+		token.Created = time.Now()
+	}
+}
+
+/***** exported functions *********************************************************/
+
+// IsValid returns true if the reset token has not been used and has not expired
+func (token *ResetToken) IsValid() bool {
+	return token.Used.IsZero() && time.Now().Before(token.Expires)
+}
+
+/***** PasswordResetRequest *******************************************************/
+
+// PasswordResetRequest asks for a password reset link to be sent to the user
+type PasswordResetRequest struct {
+	Username string `json:"username"`
+}
+
+/***** PasswordReset **************************************************************/
+
+// PasswordReset sets a new password using a password reset token
+type PasswordReset struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+/**********************************************************************************/
@@ -0,0 +1,177 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+const subjectRevocationPrefix = "sub:"
+
+/**********************************************************************************/
+
+// NewTokenRevocation creates a revocation for a single token identified by its
+// jti claim, the revocation is only needed until the token expires
+func NewTokenRevocation(tokenId, subject string, expires time.Time, reason string) *Revocation {
+	return &Revocation{
+		Created: time.Now(),
+		Expires: expires,
+		TokenID: tokenId,
+		Subject: subject,
+		Reason:  reason,
+	}
+}
+
+// NewSubjectRevocation creates a revocation for every token issued to the subject
+// up to now
+func NewSubjectRevocation(subject, reason string) *Revocation {
+	return &Revocation{
+		Created: time.Now(),
+		TokenID: SubjectRevocationId(subject),
+		Subject: subject,
+		Reason:  reason,
+	}
+}
+
+// SubjectRevocationId returns the id used to store the revocation of all tokens
+// issued to the subject
+func SubjectRevocationId(subject string) string {
+	return subjectRevocationPrefix + subject
+}
+
+/***** Revocation *****************************************************************/
+
+// Revocation records a token, or all tokens for a subject, that must no longer be
+// accepted even though they have not expired
+type Revocation struct {
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+	TokenID string    `json:"jti"`
+	Subject string    `json:"sub"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the Revocation
+func (revocation Revocation) MarshalJSON() ([]byte, error) {
+	type Alias Revocation
+
+	return json.Marshal(&struct {
+		Created int64 `json:"created"`
+		Expires int64 `json:"expires"`
+		Alias
+	}{
+		Created: revocation.Created.Unix(),
+		Expires: unixOrZero(revocation.Expires),
+		Alias:   (Alias)(revocation),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the
+// Revocation
+func (revocation *Revocation) UnmarshalJSON(data []byte) error {
+	type Alias Revocation
+	aux := &struct {
+		Created int64 `json:"created"`
+		Expires int64 `json:"expires"`
+		*Alias
+	}{
+		Alias: (*Alias)(revocation),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	revocation.Created = time.Unix(aux.Created, 0)
+	revocation.Expires = timeOrZero(aux.Expires)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (revocation *Revocation) Item() interface{} {
+	type Alias Revocation
+
+	item := &struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Created int64  `json:"created"`
+		Expires int64  `json:"expires"`
+		*Alias
+	}{
+		ID:      revocation.Id(),
+		Type:    revocation.Type(),
+		Created: revocation.Created.Unix(),
+		Expires: unixOrZero(revocation.Expires),
+		Alias:   (*Alias)(revocation),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the revocation
+func (revocation *Revocation) Id() string {
+	return revocation.TokenID
+}
+
+// Type returns the reflect Type representation of the current object
+func (revocation *Revocation) Type() string {
+	return util.GetTypeName(revocation)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (revocation *Revocation) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (revocation *Revocation) Update(user string) {
+	if revocation.Created.Unix() < 0 {
+		revocation.Created = time.Now()
+	}
+}
+
+/***** exported functions *********************************************************/
+
+// IsExpired returns true once the revoked token would have expired anyway. A
+// revocation without an expiration never expires
+func (revocation *Revocation) IsExpired() bool {
+	return !revocation.Expires.IsZero() && time.Now().After(revocation.Expires)
+}
+
+// Covers returns true if a token issued at the passed in time is revoked by a
+// subject revocation. Tokens record the second they were issued so the revocation
+// is compared at the same resolution, a token issued in the second of the
+// revocation is covered
+func (revocation *Revocation) Covers(issuedAt time.Time) bool {
+	return issuedAt.Unix() <= revocation.Created.Unix()
+}
+
+/**********************************************************************************/
@@ -0,0 +1,51 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevocationCovers(t *testing.T) {
+	revocation := &Revocation{Created: time.Unix(1000, 700000000)}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		covers   bool
+	}{
+		{name: "issued the second before", issuedAt: time.Unix(999, 0), covers: true},
+		{name: "issued long before", issuedAt: time.Unix(10, 0), covers: true},
+		{name: "issued in the same second", issuedAt: time.Unix(1000, 0), covers: true},
+		{name: "issued the second after", issuedAt: time.Unix(1001, 0), covers: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if covers := revocation.Covers(test.issuedAt); covers != test.covers {
+				t.Errorf("Covers(%d) = %t, want %t", test.issuedAt.Unix(), covers, test.covers)
+			}
+		})
+	}
+}
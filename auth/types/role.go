@@ -32,12 +32,17 @@ import (
 /**********************************************************************************/
 /***** Role ***********************************************************************/
 
-// Role defines a Role that a User holds as part of an RBAC system
+// Role defines a Role that a User holds as part of an RBAC system. A role grants
+// its own permissions along with the permissions of its parent roles, users holding
+// a role that requires MFA must log in with a second factor
 type Role struct {
 	Created     time.Time `json:"created"`
+	Permissions []string  `json:"permissions,omitempty"`
+	Parents     []string  `json:"parents,omitempty"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	Active      bool      `json:"active"`
+	RequireMFA  bool      `json:"requiremfa"`
 }
 
 /***** Marshaler interface implmentation ******************************************/
@@ -118,5 +123,104 @@ func (role *Role) Update(user string) {
 	}
 }
 
+/***** exported functions *********************************************************/
+
+// ResolvePermissions computes the effective permissions granted by the named
+// roles, including the permissions inherited from parent roles. Inactive roles
+// grant nothing and their parents are not inherited through them
+func ResolvePermissions(roles map[string]*Role, names []string) []string {
+	permissions := make([]string, 0)
+	granted := make(map[string]bool)
+	visited := make(map[string]bool)
+
+	var resolve func(name string)
+	resolve = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+
+		role, ok := roles[name]
+		if !ok || !role.Active {
+			return
+		}
+
+		for _, permission := range role.Permissions {
+			if !granted[permission] {
+				granted[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+
+		for _, parent := range role.Parents {
+			resolve(parent)
+		}
+	}
+
+	for _, name := range names {
+		resolve(name)
+	}
+
+	return permissions
+}
+
+// ActiveRoles returns the named roles that exist and are active along with the
+// active roles they inherit from, inactive roles grant no access and pass nothing
+// on from their parents
+func ActiveRoles(roles map[string]*Role, names []string) []string {
+	active := make([]string, 0, len(names))
+	visited := make(map[string]bool)
+
+	var expand func(name string)
+	expand = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+
+		role, ok := roles[name]
+		if !ok || !role.Active {
+			return
+		}
+
+		active = append(active, name)
+		for _, parent := range role.Parents {
+			expand(parent)
+		}
+	}
+
+	for _, name := range names {
+		expand(name)
+	}
+
+	return active
+}
+
+// InheritsFrom returns true if the role named ancestor is reachable through the
+// parents of the role, used to keep the role hierarchy free of cycles
+func InheritsFrom(roles map[string]*Role, role *Role, ancestor string) bool {
+	visited := make(map[string]bool)
+	pending := append([]string{}, role.Parents...)
+
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+
+		if name == ancestor {
+			return true
+		}
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+
+		if parent, ok := roles[name]; ok {
+			pending = append(pending, parent.Parents...)
+		}
+	}
+
+	return false
+}
+
 /**********************************************************************************/
 /**********************************************************************************/
@@ -0,0 +1,62 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestActiveRoles(t *testing.T) {
+	roles := map[string]*Role{
+		"admin":    {Name: "admin", Active: true},
+		"user":     {Name: "user", Active: true},
+		"disabled": {Name: "disabled", Active: false},
+		"editor":   {Name: "editor", Active: true, Parents: []string{"user"}},
+		"lead":     {Name: "lead", Active: true, Parents: []string{"editor", "disabled"}},
+		"retired":  {Name: "retired", Active: false, Parents: []string{"admin"}},
+	}
+
+	tests := []struct {
+		name   string
+		names  []string
+		active []string
+	}{
+		{name: "active roles", names: []string{"admin", "user"}, active: []string{"admin", "user"}},
+		{name: "inactive role", names: []string{"disabled", "user"}, active: []string{"user"}},
+		{name: "unknown role", names: []string{"missing"}, active: []string{}},
+		{name: "no roles", names: nil, active: []string{}},
+		{name: "inherited role", names: []string{"editor"}, active: []string{"editor", "user"}},
+		{name: "inherited through several parents", names: []string{"lead"}, active: []string{"lead", "editor", "user"}},
+		{name: "inherited role held directly", names: []string{"user", "editor"}, active: []string{"user", "editor"}},
+		{name: "parent of an inactive role", names: []string{"retired"}, active: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if active := ActiveRoles(roles, test.names); !reflect.DeepEqual(active, test.active) {
+				t.Errorf("ActiveRoles(%v) = %v, want %v", test.names, active, test.active)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sdbeard/go-supportlib/common/util"
+)
+
+/***** Session ********************************************************************/
+
+// Session is a login session kept on the server, the session cookie only holds the
+// signed session id. Data holds the encoded session values
+type Session struct {
+	Created    time.Time `json:"created"`
+	LastActive time.Time `json:"lastactive"`
+	Expires    time.Time `json:"expires"`
+	ID         string    `json:"sessionid"`
+	Subject    string    `json:"sub"`
+	Family     string    `json:"fam"`
+	Address    string    `json:"address"`
+	UserAgent  string    `json:"useragent"`
+	Data       []byte    `json:"data"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the Session
+func (session Session) MarshalJSON() ([]byte, error) {
+	type Alias Session
+
+	return json.Marshal(&struct {
+		Created    int64 `json:"created"`
+		LastActive int64 `json:"lastactive"`
+		Expires    int64 `json:"expires"`
+		Alias
+	}{
+		Created:    session.Created.Unix(),
+		LastActive: unixOrZero(session.LastActive),
+		Expires:    session.Expires.Unix(),
+		Alias:      (Alias)(session),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the Session
+func (session *Session) UnmarshalJSON(data []byte) error {
+	type Alias Session
+	aux := &struct {
+		Created    int64 `json:"created"`
+		LastActive int64 `json:"lastactive"`
+		Expires    int64 `json:"expires"`
+		*Alias
+	}{
+		Alias: (*Alias)(session),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	session.Created = time.Unix(aux.Created, 0)
+	session.LastActive = timeOrZero(aux.LastActive)
+	session.Expires = time.Unix(aux.Expires, 0)
+
+	return nil
+}
+
+/***** Datasource Document interface implementation *******************************/
+
+// Item returns an object that represents the object to stored
+func (session *Session) Item() interface{} {
+	type Alias Session
+
+	item := &struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Created    int64  `json:"created"`
+		LastActive int64  `json:"lastactive"`
+		Expires    int64  `json:"expires"`
+		*Alias
+	}{
+		ID:         session.Id(),
+		Type:       session.Type(),
+		Created:    session.Created.Unix(),
+		LastActive: unixOrZero(session.LastActive),
+		Expires:    session.Expires.Unix(),
+		Alias:      (*Alias)(session),
+	}
+
+	return item
+}
+
+// ID returns the key/id to query and identify the session
+func (session *Session) Id() string {
+	return session.ID
+}
+
+// Type returns the reflect Type representation of the current object
+func (session *Session) Type() string {
+	return util.GetTypeName(session)
+}
+
+// IdKey returns the specific key used to query an object by ID
+func (session *Session) IdKey() string {
+	return "id"
+}
+
+// Updates the state of the document if necessary
+func (session *Session) Update(user string) {
+	if session.Created.Unix() < 0 {
+		session.Created = time.Now()
+	}
+}
+
+/***** exported functions *********************************************************/
+
+// IsExpired returns true if the session can no longer be used
+func (session *Session) IsExpired() bool {
+	return time.Now().After(session.Expires)
+}
+
+/***** SessionInfo ****************************************************************/
+
+// NewSessionInfo creates the response representation of the session, current is
+// set for the session the request was made with
+func NewSessionInfo(session *Session, current string) *SessionInfo {
+	return &SessionInfo{
+		Created:    session.Created,
+		LastActive: session.LastActive,
+		Expires:    session.Expires,
+		ID:         session.ID,
+		Subject:    session.Subject,
+		Address:    session.Address,
+		UserAgent:  session.UserAgent,
+		Current:    session.ID == current,
+	}
+}
+
+// NewSessionInfoList creates the response representation of each of the sessions
+func NewSessionInfoList(sessions []*Session, current string) []*SessionInfo {
+	infoList := make([]*SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infoList = append(infoList, NewSessionInfo(session, current))
+	}
+
+	return infoList
+}
+
+// SessionInfo is the representation of a session returned by the service, it
+// never includes the session values
+type SessionInfo struct {
+	Created    time.Time `json:"created"`
+	LastActive time.Time `json:"lastactive"`
+	Expires    time.Time `json:"expires"`
+	ID         string    `json:"id"`
+	Subject    string    `json:"sub"`
+	Address    string    `json:"address"`
+	UserAgent  string    `json:"useragent"`
+	Current    bool      `json:"current"`
+}
+
+// MarshalJSON is a method allowing serialization of the SessionInfo
+func (info SessionInfo) MarshalJSON() ([]byte, error) {
+	type Alias SessionInfo
+
+	return json.Marshal(&struct {
+		Created    int64 `json:"created"`
+		LastActive int64 `json:"lastactive"`
+		Expires    int64 `json:"expires"`
+		Alias
+	}{
+		Created:    info.Created.Unix(),
+		LastActive: unixOrZero(info.LastActive),
+		Expires:    info.Expires.Unix(),
+		Alias:      (Alias)(info),
+	})
+}
+
+/**********************************************************************************/
+/**********************************************************************************/
@@ -24,13 +24,39 @@ package types
 
 import (
 	"encoding/json"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/sdbeard/go-supportlib/common/util"
 )
 
+// reservedClaims are the claims set by the service that a user's custom claims
+// can't use
+var reservedClaims = []string{
+	"sub", "jti", "iss", "aud", "exp", "nbf", "iat", "typ", "authorized", "org",
+	"roles", "permissions", "email_verified", "client_id", "scope", "api_key",
+	TokenUseClaim,
+}
+
 /**********************************************************************************/
 
+// IsReservedClaim returns true if the claim is set by the service
+func IsReservedClaim(name string) bool {
+	return slices.Contains(reservedClaims, name)
+}
+
+// ValidateClaims checks that none of the custom claims are reserved claims
+func ValidateClaims(claims map[string]interface{}) error {
+	for name := range claims {
+		if IsReservedClaim(name) {
+			return fmt.Errorf("the claim %s is reserved", name)
+		}
+	}
+
+	return nil
+}
+
 // NewUser creates a new user object and returns the reference
 func NewUser() *User {
 	return &User{
@@ -45,13 +71,15 @@ func NewUser() *User {
 
 // AuthUser
 type User struct {
-	Profile      *UserProfile           `json:"profile,omitempty"`
-	Claims       map[string]interface{} `json:"claims,omitempty"`
-	Roles        []string               `json:"roles"`
-	Created      time.Time              `json:"created"`
-	Username     string                 `json:"username"`
-	Password     string                 `json:"password"`
-	Organization string                 `json:"org"`
+	Profile       *UserProfile           `json:"profile,omitempty"`
+	MFA           *MFASettings           `json:"mfa,omitempty"`
+	Claims        map[string]interface{} `json:"claims,omitempty"`
+	Roles         []string               `json:"roles"`
+	Created       time.Time              `json:"created"`
+	Username      string                 `json:"username"`
+	Password      string                 `json:"password"`
+	Organization  string                 `json:"org"`
+	EmailVerified bool                   `json:"emailverified"`
 }
 
 /***** Marshaler interfaces *******************************************************/
@@ -133,5 +161,21 @@ func (user *User) Update(username string) {
 }
 
 /***** exported functions *********************************************************/
+
+// Email returns the email address of the user, the username is used when the
+// profile has no email address
+func (user *User) Email() string {
+	if user.Profile != nil && user.Profile.Email != "" {
+		return user.Profile.Email
+	}
+
+	return user.Username
+}
+
+// MFAEnabled returns true if the user has confirmed multi-factor authentication
+func (user *User) MFAEnabled() bool {
+	return user.MFA != nil && user.MFA.Enabled
+}
+
 /**********************************************************************************/
 /**********************************************************************************/
@@ -0,0 +1,145 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+/**********************************************************************************/
+
+// NewUserInfo creates the response representation of the user, leaving out the
+// credentials
+func NewUserInfo(user *User) *UserInfo {
+	return &UserInfo{
+		Profile:       user.Profile,
+		Claims:        user.Claims,
+		Roles:         user.Roles,
+		Created:       user.Created,
+		Username:      user.Username,
+		Organization:  user.Organization,
+		EmailVerified: user.EmailVerified,
+		MFAEnabled:    user.MFAEnabled(),
+	}
+}
+
+// NewUserInfoList creates the response representation of each of the users
+func NewUserInfoList(users []*User) []*UserInfo {
+	infoList := make([]*UserInfo, 0, len(users))
+	for _, user := range users {
+		infoList = append(infoList, NewUserInfo(user))
+	}
+
+	return infoList
+}
+
+/***** UserInfo *******************************************************************/
+
+// UserInfo is the representation of a user returned by the service, it never
+// includes the user's credentials
+type UserInfo struct {
+	Profile       *UserProfile           `json:"profile,omitempty"`
+	Claims        map[string]interface{} `json:"claims,omitempty"`
+	Roles         []string               `json:"roles"`
+	Created       time.Time              `json:"created"`
+	Username      string                 `json:"username"`
+	Organization  string                 `json:"org"`
+	EmailVerified bool                   `json:"emailverified"`
+	MFAEnabled    bool                   `json:"mfaenabled"`
+}
+
+/***** Marshaler interfaces *******************************************************/
+
+// MarshalJSON is a method allowing serialization of the UserInfo
+func (info UserInfo) MarshalJSON() ([]byte, error) {
+	type Alias UserInfo
+
+	return json.Marshal(&struct {
+		Created int64 `json:"created"`
+		Alias
+	}{
+		Created: info.Created.Unix(),
+		Alias:   (Alias)(info),
+	})
+}
+
+// UnmarshalJSON is a method implemented allowing de-serialization of the
+// UserInfo
+func (info *UserInfo) UnmarshalJSON(data []byte) error {
+	type Alias UserInfo
+	aux := &struct {
+		Created int64 `json:"created"`
+		*Alias
+	}{
+		Alias: (*Alias)(info),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	info.Created = time.Unix(aux.Created, 0)
+
+	return nil
+}
+
+/***** UserPatch ******************************************************************/
+
+// UserPatch holds the fields of a user to change in a partial update, fields that
+// are not set are left unchanged. The password can't be changed with a patch
+type UserPatch struct {
+	Profile      *UserProfile           `json:"profile,omitempty"`
+	Claims       map[string]interface{} `json:"claims,omitempty"`
+	Roles        *[]string              `json:"roles,omitempty"`
+	Organization *string                `json:"org,omitempty"`
+}
+
+/***** exported functions *********************************************************/
+
+// Apply sets the fields of the patch on the user
+func (patch *UserPatch) Apply(user *User) {
+	if patch.Profile != nil {
+		user.Profile = patch.Profile
+	}
+	if patch.Claims != nil {
+		user.Claims = patch.Claims
+	}
+	if patch.Roles != nil {
+		user.Roles = *patch.Roles
+	}
+	if patch.Organization != nil {
+		user.Organization = *patch.Organization
+	}
+}
+
+/***** PasswordChange *************************************************************/
+
+// PasswordChange holds the new password for a user, along with the current
+// password when users change their own password
+type PasswordChange struct {
+	Current  string `json:"current,omitempty"`
+	Password string `json:"password"`
+}
+
+/**********************************************************************************/
@@ -0,0 +1,167 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+package verify
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sdbeard/common-services/auth/types"
+)
+
+// minKeyRefresh limits how often an unknown key id fetches the keys again so that
+// made up key ids can't flood the auth service
+const minKeyRefresh = 10 * time.Second
+
+// asymmetricMethods are the signing methods of the keys the auth service publishes
+var asymmetricMethods = []string{"RS256", "ES256", "EdDSA"}
+
+/***** keySet *********************************************************************/
+
+// keySet caches the public keys published by the auth service, the keys are
+// fetched again when they are stale or a token names a key id that isn't cached
+type keySet struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+	lock    sync.Mutex
+	keys    map[string]*verifyKey
+	fetched time.Time
+}
+
+type verifyKey struct {
+	algorithm string
+	key       crypto.PublicKey
+}
+
+func newKeySet(url string, refresh time.Duration, client *http.Client) *keySet {
+	return &keySet{
+		url:     url,
+		refresh: refresh,
+		client:  client,
+		keys:    make(map[string]*verifyKey),
+	}
+}
+
+/**********************************************************************************/
+
+// key returns the public key with the key id, the algorithm of the token must
+// match the algorithm the key was published with
+func (set *keySet) key(ctx context.Context, kid, algorithm string) (crypto.PublicKey, error) {
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	key, ok := set.keys[kid]
+	stale := time.Since(set.fetched) >= set.refresh
+	if stale || (!ok && time.Since(set.fetched) >= minKeyRefresh) {
+		if err := set.fetch(ctx); err != nil {
+			// The cached keys are used while the auth service can't be reached
+			if !ok {
+				return nil, err
+			}
+		} else {
+			key, ok = set.keys[kid]
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %s", kid)
+	}
+	if key.algorithm != "" && key.algorithm != algorithm {
+		return nil, fmt.Errorf("signing key %s is not a %s key", kid, algorithm)
+	}
+
+	return key.key, nil
+}
+
+func (set *keySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, set.url, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := set.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("the key set returned status %d", response.StatusCode)
+	}
+
+	jwks := new(types.JWKSet)
+	if err := json.NewDecoder(response.Body).Decode(jwks); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*verifyKey)
+	for _, jwk := range jwks.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &verifyKey{algorithm: jwk.Alg, key: key}
+	}
+
+	set.keys = keys
+	set.fetched = time.Now()
+
+	return nil
+}
+
+/**********************************************************************************/
+
+// parse verifies the token with the published keys and returns its claims, the
+// token must be an access token with an expiration
+func (verifier *Verifier) parse(ctx context.Context, tokenString string) (map[string]interface{}, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return verifier.keys.key(ctx, kid, token.Method.Alg())
+	}, jwt.WithValidMethods(asymmetricMethods))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	expires, err := claims.GetExpirationTime()
+	if err != nil || expires == nil {
+		return nil, fmt.Errorf("%w: the token has no expiration", ErrInvalidToken)
+	}
+
+	// id_tokens are signed with the same keys but are not access tokens
+	if use, _ := claims[types.TokenUseClaim].(string); use != types.TokenUseAccess {
+		return nil, fmt.Errorf("%w: the token is not an access token", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+/**********************************************************************************/
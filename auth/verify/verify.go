@@ -0,0 +1,198 @@
+// *********************************************************************************
+// The MIT License (MIT)
+//
+// # Copyright (c) 2023 Sean Beard
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in the
+// Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+// *********************************************************************************
+// Package verify checks the access tokens issued by the auth service so the other
+// services can authenticate their callers without calling into the auth service's
+// secrets. The Verifier is added to an alice chain:
+//
+//	verifier, err := verify.New(verify.Options{
+//		JWKSURL:  "http://127.0.0.1:8000/.well-known/jwks.json",
+//		Audience: "email",
+//	})
+//	chain := alice.New(handlers.LoggingHandler, verifier.Middleware)
+package verify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sdbeard/common-services/auth/types"
+	"github.com/unrolled/render"
+)
+
+const (
+	// APIKeyHeader is the header API keys are presented in
+	APIKeyHeader = "X-API-Key"
+
+	defaultKeyRefresh = 5 * time.Minute
+	defaultTimeout    = 10 * time.Second
+)
+
+var (
+	// ErrNoToken is returned when the request has no token
+	ErrNoToken = errors.New("no authorization information found")
+	// ErrInvalidToken is returned when the token is not valid, has expired or has
+	// been revoked
+	ErrInvalidToken = errors.New("the token is not valid")
+	// ErrAudience is returned when the token was not issued for the audience
+	ErrAudience = errors.New("the token was not issued for this audience")
+)
+
+/**********************************************************************************/
+
+// Options configures how the tokens are verified. Tokens are verified locally with
+// the published keys when the JWKSURL is set and sent to the IntrospectionURL
+// otherwise. API keys, and tokens signed with an HMAC key, can only be checked
+// with introspection, which also catches tokens revoked before they expire
+type Options struct {
+	JWKSURL          string
+	IntrospectionURL string
+	// ClientID and ClientSecret are the credentials of the confidential client the
+	// introspection endpoint is called with
+	ClientID     string
+	ClientSecret string
+	// Audience is checked against the aud claim of the token when it is set
+	Audience string
+	// KeyRefresh is how long the published keys are cached
+	KeyRefresh time.Duration
+	Client     *http.Client
+}
+
+// New creates the Verifier for the options
+func New(options Options) (*Verifier, error) {
+	if options.JWKSURL == "" && options.IntrospectionURL == "" {
+		return nil, errors.New("either the JWKS url or the introspection url is required")
+	}
+	if options.IntrospectionURL != "" && (options.ClientID == "" || options.ClientSecret == "") {
+		return nil, errors.New("the introspection url requires the client id and secret")
+	}
+
+	if options.KeyRefresh <= 0 {
+		options.KeyRefresh = defaultKeyRefresh
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	verifier := &Verifier{
+		options: options,
+		render:  render.New(),
+	}
+	if options.JWKSURL != "" {
+		verifier.keys = newKeySet(options.JWKSURL, options.KeyRefresh, options.Client)
+	}
+
+	return verifier, nil
+}
+
+// PrincipalFrom returns the principal the Verifier stored in the context, nil is
+// returned when the request was not verified
+func PrincipalFrom(ctx context.Context) *types.Principal {
+	return types.PrincipalFrom(ctx)
+}
+
+/***** Verifier *******************************************************************/
+
+// Verifier verifies the tokens presented to a service
+type Verifier struct {
+	options Options
+	keys    *keySet
+	render  *render.Render
+}
+
+/***** exported functions *********************************************************/
+
+// Middleware refuses requests without a valid token and stores the principal of
+// the caller in the request context
+func (verifier *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		token, method := tokenFromRequest(req)
+		if token == "" {
+			verifier.render.JSON(res, http.StatusUnauthorized, ErrNoToken.Error())
+			return
+		}
+
+		principal, err := verifier.verify(req.Context(), token, method)
+		switch {
+		case errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrAudience):
+			verifier.render.JSON(res, http.StatusUnauthorized, err.Error())
+			return
+		case err != nil:
+			verifier.render.JSON(res, http.StatusBadGateway, err.Error())
+			return
+		}
+
+		next.ServeHTTP(res, req.WithContext(types.WithPrincipal(req.Context(), principal)))
+	})
+}
+
+// Verify checks the access token and returns the principal it was issued to
+func (verifier *Verifier) Verify(ctx context.Context, token string) (*types.Principal, error) {
+	return verifier.verify(ctx, token, types.AuthMethodBearer)
+}
+
+/**********************************************************************************/
+
+func (verifier *Verifier) verify(ctx context.Context, token, method string) (*types.Principal, error) {
+	var claims map[string]interface{}
+	var err error
+
+	if verifier.keys != nil && method != types.AuthMethodAPIKey {
+		claims, err = verifier.parse(ctx, token)
+	} else {
+		claims, err = verifier.introspect(ctx, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	principal := types.NewPrincipal(claims, method)
+	if verifier.options.Audience != "" && !slices.Contains(principal.Audience, verifier.options.Audience) {
+		return nil, ErrAudience
+	}
+
+	return principal, nil
+}
+
+// tokenFromRequest returns the token from the API key header, the authorization
+// header or the auth cookie, in that order
+func tokenFromRequest(req *http.Request) (string, string) {
+	if apiKey := req.Header.Get(APIKeyHeader); apiKey != "" {
+		return apiKey, types.AuthMethodAPIKey
+	}
+
+	if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+		return token, types.AuthMethodBearer
+	}
+
+	if cookie, err := req.Cookie("auth"); err == nil {
+		return cookie.Value, types.AuthMethodCookie
+	}
+
+	return "", ""
+}
+
+/**********************************************************************************/